@@ -0,0 +1,100 @@
+package shelltoken
+
+import (
+	"fmt"
+)
+
+// QuoteStyle summarizes how a token was quoted.
+type QuoteStyle uint8
+
+const (
+	// QuoteBare is a token without any quotes, it may contain escapes.
+	QuoteBare QuoteStyle = iota
+
+	// QuoteSingle is a token consisting of single quoted text only.
+	QuoteSingle
+
+	// QuoteDouble is a token consisting of double quoted text only.
+	QuoteDouble
+
+	// QuoteMixed is a token combining different quotes or quoted and unquoted text, ex.: a"b"'c'.
+	QuoteMixed
+)
+
+func (s QuoteStyle) String() string {
+	switch s {
+	case QuoteBare:
+		return "Bare"
+	case QuoteSingle:
+		return "Single"
+	case QuoteDouble:
+		return "Double"
+	case QuoteMixed:
+		return "Mixed"
+	}
+
+	return fmt.Sprintf("QuoteStyle(%d)", s)
+}
+
+// AnnotatedToken is a token along with the quoting used in the input.
+type AnnotatedToken struct {
+	Value   string
+	Quoting QuoteStyle
+}
+
+// SplitQuotesAnnotated works like SplitQuotes and additionally returns the
+// quote style of each token. Quote characters other than double quotes count
+// as single quotes.
+func SplitQuotesAnnotated(str, sep string, options ...SplitOption) ([]AnnotatedToken, error) {
+	cfg := NewConfig(sep, options...)
+	pst := newParseState(&cfg)
+	pst.recordSpans = true
+
+	tokens := []AnnotatedToken{}
+	seen := 0
+
+	err := pst.parse(str, func(token string, start, end int) bool {
+		// all spans closed since the previous token belong to this token
+		tokens = append(tokens, AnnotatedToken{Value: token, Quoting: quoteStyle(pst.spans[seen:], start, end)})
+		seen = len(pst.spans)
+
+		return true
+	})
+	if err != nil && !pst.continueOnError(err) {
+		return nil, err
+	}
+
+	return tokens, err
+}
+
+// quoteStyle returns the quote style of the token from start to end based on its spans.
+func quoteStyle(spans []Span, start, end int) QuoteStyle {
+	style := QuoteBare
+	quoted := 0
+
+	for _, span := range spans {
+		var kind QuoteStyle
+
+		switch span.Kind {
+		case SpanSingle:
+			kind = QuoteSingle
+		case SpanDouble:
+			kind = QuoteDouble
+		default:
+			continue
+		}
+
+		if style != QuoteBare && style != kind {
+			return QuoteMixed
+		}
+
+		style = kind
+		quoted += span.End - span.Start
+	}
+
+	if style != QuoteBare && quoted != end-start {
+		return QuoteMixed
+	}
+
+	return style
+}
@@ -0,0 +1,55 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitQuotesAnnotated(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []shelltoken.AnnotatedToken
+	}{
+		{"", []shelltoken.AnnotatedToken{}},
+		{`a 'b c' "d" a"b"'c'`, []shelltoken.AnnotatedToken{
+			{Value: "a", Quoting: shelltoken.QuoteBare},
+			{Value: "b c", Quoting: shelltoken.QuoteSingle},
+			{Value: "d", Quoting: shelltoken.QuoteDouble},
+			{Value: "abc", Quoting: shelltoken.QuoteMixed},
+		}},
+		{`a\ b 'a''b' "a\"b" "a"b '' ""`, []shelltoken.AnnotatedToken{
+			{Value: "a b", Quoting: shelltoken.QuoteBare},
+			{Value: "ab", Quoting: shelltoken.QuoteSingle},
+			{Value: `a"b`, Quoting: shelltoken.QuoteDouble},
+			{Value: "ab", Quoting: shelltoken.QuoteMixed},
+			{Value: "", Quoting: shelltoken.QuoteSingle},
+			{Value: "", Quoting: shelltoken.QuoteDouble},
+		}},
+		{`"a"'b'`, []shelltoken.AnnotatedToken{
+			{Value: "ab", Quoting: shelltoken.QuoteMixed},
+		}},
+	}
+
+	for _, tst := range tests {
+		res, err := shelltoken.SplitQuotesAnnotated(tst.in, shelltoken.Whitespace)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, res, "Tokenize: %v -> %v", tst.in, res)
+	}
+
+	res, err := shelltoken.SplitQuotesAnnotated(`'a b' c`, shelltoken.Whitespace, shelltoken.SplitKeepSeparator|shelltoken.SplitKeepQuotes)
+	require.NoError(t, err)
+	assert.Equal(t, []shelltoken.AnnotatedToken{
+		{Value: "'a b'", Quoting: shelltoken.QuoteSingle},
+		{Value: " ", Quoting: shelltoken.QuoteBare},
+		{Value: "c", Quoting: shelltoken.QuoteBare},
+	}, res)
+
+	_, err = shelltoken.SplitQuotesAnnotated(`a "b`, shelltoken.Whitespace)
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+
+	assert.Equal(t, "Mixed", shelltoken.QuoteMixed.String())
+	assert.Equal(t, "QuoteStyle(9)", shelltoken.QuoteStyle(9).String())
+}
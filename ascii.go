@@ -0,0 +1,37 @@
+package shelltoken
+
+// asciiSet is a bitmap of ascii characters.
+type asciiSet [2]uint64
+
+// specialASCII contains the ascii characters which have a special meaning with
+// some options: escapes, substitutions, redirections, expansions, tabs and the
+// default shell characters.
+var specialASCII = newASCIISet("\\$`<>&(=~!\r\t" + OutsideQuoteShellCharacters + DoubleQuoteShellCharacters)
+
+// newASCIISet returns a set containing the ascii characters of str.
+func newASCIISet(str string) asciiSet {
+	var set asciiSet
+	set.addAll(str)
+
+	return set
+}
+
+// add adds char to the set, non-ascii characters are ignored.
+func (s *asciiSet) add(char rune) {
+	if char >= 0 && char < 128 {
+		s[char/64] |= 1 << (char % 64)
+	}
+}
+
+// addAll adds all ascii characters of str to the set.
+func (s *asciiSet) addAll(str string) {
+	// bytes of multi byte characters are no ascii characters
+	for i := range len(str) {
+		s.add(rune(str[i]))
+	}
+}
+
+// contains returns true if char is in the set.
+func (s *asciiSet) contains(char rune) bool {
+	return char >= 0 && char < 128 && s[char/64]&(1<<(char%64)) != 0
+}
@@ -1,6 +1,8 @@
 package shelltoken_test
 
 import (
+	"context"
+	"strings"
 	"testing"
 
 	"github.com/sni/shelltoken"
@@ -23,3 +25,146 @@ func BenchmarkParseLong(b *testing.B) {
 		shelltoken.SplitLinux(tst)
 	}
 }
+
+func BenchmarkSplitQuotesFromBytes(b *testing.B) {
+	buf := []byte(`/usr/lib/nagios/plugins/check_http -H localhost -u '/index.html' --string "it works" -w 5 -c 10`)
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		shelltoken.SplitQuotes(string(buf), shelltoken.Whitespace)
+	}
+}
+
+func BenchmarkSplitQuotesBytes(b *testing.B) {
+	buf := []byte(`/usr/lib/nagios/plugins/check_http -H localhost -u '/index.html' --string "it works" -w 5 -c 10`)
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		shelltoken.SplitQuotesBytes(buf, shelltoken.Whitespace)
+	}
+}
+
+func BenchmarkSplitQuotesCommand(b *testing.B) {
+	tst := `/usr/lib/nagios/plugins/check_http -H localhost -u '/index.html' --string "it works" -w 5 -c 10`
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		shelltoken.SplitQuotes(tst, shelltoken.Whitespace)
+	}
+}
+
+func BenchmarkSplitterCommand(b *testing.B) {
+	tst := `/usr/lib/nagios/plugins/check_http -H localhost -u '/index.html' --string "it works" -w 5 -c 10`
+	splitter := shelltoken.NewSplitter(shelltoken.Whitespace)
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		splitter.Split(tst)
+	}
+}
+
+func BenchmarkValidateCommand(b *testing.B) {
+	tst := `/usr/lib/nagios/plugins/check_http -H localhost -u '/index.html' --string "it works" -w 5 -c 10`
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		shelltoken.Validate(tst, shelltoken.Whitespace)
+	}
+}
+
+func BenchmarkSplitManyTokens(b *testing.B) {
+	tst := strings.Repeat("word ", 1000)
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace)
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		cfg.Split(tst)
+	}
+}
+
+func BenchmarkSplitManyTokensCapacityHint(b *testing.B) {
+	tst := strings.Repeat("word ", 1000)
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace).WithCapacityHint(1000)
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		cfg.Split(tst)
+	}
+}
+
+func BenchmarkSplitQuotesContextCommand(b *testing.B) {
+	tst := `/usr/lib/nagios/plugins/check_http -H localhost -u '/index.html' --string "it works" -w 5 -c 10`
+	ctx := context.Background()
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		shelltoken.SplitQuotesContext(ctx, tst, shelltoken.Whitespace)
+	}
+}
+
+func BenchmarkFirstTokenLong(b *testing.B) {
+	tst := "/usr/bin/cmd " + strings.Repeat(`--option "quoted value" `, 1000)
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		shelltoken.FirstToken(tst, shelltoken.Whitespace)
+	}
+}
+
+func BenchmarkFirstTokenLongSplitQuotes(b *testing.B) {
+	tst := "/usr/bin/cmd " + strings.Repeat(`--option "quoted value" `, 1000)
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		argv, _ := shelltoken.SplitQuotes(tst, shelltoken.Whitespace)
+		_ = argv[0]
+	}
+}
+
+func BenchmarkSplitPlain(b *testing.B) {
+	tst := `/usr/lib/nagios/plugins/check_http -H localhost -u /index.html --string works -w 5 -c 10`
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace)
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		cfg.Split(tst)
+	}
+}
+
+func BenchmarkSplitPlainGeneral(b *testing.B) {
+	tst := `/usr/lib/nagios/plugins/check_http -H localhost -u /index.html --string works -w 5 -c 10`
+	// SplitHeredoc does not change the result for this input but disables the plain split
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace, shelltoken.SplitHeredoc)
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		cfg.Split(tst)
+	}
+}
+
+func BenchmarkTokenizerLongToken(b *testing.B) {
+	tst := "cmd '" + strings.Repeat("quoted text\n", 100000) + "' arg"
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		tokenizer := shelltoken.NewTokenizer(strings.NewReader(tst), shelltoken.Whitespace)
+		for {
+			if _, err := tokenizer.Next(); err != nil {
+				break
+			}
+		}
+	}
+}
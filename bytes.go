@@ -0,0 +1,39 @@
+package shelltoken
+
+import (
+	"unsafe"
+)
+
+// SplitQuotesBytes works like SplitQuotes but tokenizes a byte slice without
+// converting it into a string first.
+// Tokens which equal their raw input, ex.: words without quotes or escape characters,
+// are returned as sub-slices of b and share its memory. All other tokens are freshly
+// allocated. b must not be modified while SplitQuotesBytes is running.
+func SplitQuotesBytes(b []byte, sep string, options ...SplitOption) (argv [][]byte, err error) {
+	// str is only read during parsing and never escapes into the result
+	str := unsafe.String(unsafe.SliceData(b), len(b))
+
+	// modified tokens are never longer than the input in total, so they all fit into a single buffer
+	data := make([]byte, 0, len(b))
+
+	argv = [][]byte{}
+	cfg := NewConfig(sep, options...)
+	pst := newParseState(&cfg)
+	pst.borrowTokens = true
+
+	err = pst.parse(str, func(token string, start, end int) bool {
+		if unsafe.StringData(token) == unsafe.StringData(str[start:end]) {
+			argv = append(argv, b[start:end:end])
+		} else {
+			data = append(data, token...)
+			argv = append(argv, data[len(data)-len(token):len(data):len(data)])
+		}
+
+		return true
+	})
+	if err != nil && !pst.continueOnError(err) {
+		return nil, err
+	}
+
+	return argv, err
+}
@@ -0,0 +1,55 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitQuotesBytes(t *testing.T) {
+	tests := []struct {
+		in      string
+		options shelltoken.SplitOption
+	}{
+		{"", 0},
+		{"a b c", 0},
+		{`echo "a b" 'c d' e\ f "" x`, 0},
+		{"  multi\nline\t'input é'  ", 0},
+		{`a "b" c`, shelltoken.SplitKeepQuotes | shelltoken.SplitKeepSeparator},
+		{`a | b`, shelltoken.SplitContinueOnShellCharacters},
+	}
+
+	for _, tst := range tests {
+		expect, expectErr := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, tst.options)
+		argv, err := shelltoken.SplitQuotesBytes([]byte(tst.in), shelltoken.Whitespace, tst.options)
+		assert.Equalf(t, expectErr, err, "error for: %s", tst.in)
+
+		tokens := []string{}
+		for _, token := range argv {
+			tokens = append(tokens, string(token))
+		}
+
+		assert.Equalf(t, expect, tokens, "Tokenize: %v -> %v", tst.in, tokens)
+	}
+
+	argv, err := shelltoken.SplitQuotesBytes([]byte(`a "b`), shelltoken.Whitespace)
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+	assert.Nil(t, argv)
+}
+
+func TestSplitQuotesBytesSubSlices(t *testing.T) {
+	buf := []byte(`plain "quoted"`)
+	argv, err := shelltoken.SplitQuotesBytes(buf, shelltoken.Whitespace)
+	require.NoError(t, err)
+	require.Len(t, argv, 2)
+
+	// unmodified tokens share the input memory
+	assert.Same(t, &buf[0], &argv[0][0])
+	assert.Equal(t, 5, cap(argv[0]))
+
+	// modified tokens are copies
+	assert.Equal(t, "quoted", string(argv[1]))
+	assert.NotSame(t, &buf[7], &argv[1][0])
+}
@@ -0,0 +1,106 @@
+package shelltoken
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TokenKind classifies a token returned by Classify.
+type TokenKind uint8
+
+const (
+	// TokenWord is a regular word.
+	TokenWord TokenKind = iota
+
+	// TokenOperator is an operator, requires SplitOperators or Config.Operators.
+	TokenOperator
+
+	// TokenSeparator is a kept separator, requires SplitKeepSeparator.
+	TokenSeparator
+
+	// TokenComment is a comment including the comment character, requires SplitStripComments.
+	TokenComment
+
+	// TokenAssignment is a leading environment assignment like "A=1" of a command.
+	TokenAssignment
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenWord:
+		return "Word"
+	case TokenOperator:
+		return "Operator"
+	case TokenSeparator:
+		return "Separator"
+	case TokenComment:
+		return "Comment"
+	case TokenAssignment:
+		return "Assignment"
+	}
+
+	return fmt.Sprintf("TokenKind(%d)", k)
+}
+
+// ClassifiedToken is a token along with its kind and position in the input string.
+type ClassifiedToken struct {
+	Value string
+	Kind  TokenKind
+	Start int // byte offset of the first character of the raw token
+	End   int // byte offset one past the last character of the raw token
+}
+
+// Classify works like SplitQuotesWithPositions but returns the kind of each token.
+// Comments are returned as tokens instead of being stripped. Words are
+// assignments if they assign a valid variable name and are only preceded by
+// other assignments since the start or the last operator.
+func Classify(str, sep string, options ...SplitOption) ([]ClassifiedToken, error) {
+	cfg := NewConfig(sep, options...)
+	pst := newParseState(&cfg)
+	pst.keepComments = true
+
+	tokens := []ClassifiedToken{}
+	leading := true
+
+	err := pst.parse(str, func(token string, start, end int) bool {
+		kind := pst.tokenKind(str[start:end])
+
+		switch kind {
+		case TokenOperator:
+			leading = true
+		case TokenWord:
+			if leading && isEnvAssignment(token) {
+				kind = TokenAssignment
+			} else {
+				leading = false
+			}
+		}
+
+		tokens = append(tokens, ClassifiedToken{Value: token, Kind: kind, Start: start, End: end})
+
+		return true
+	})
+	if err != nil && !pst.continueOnError(err) {
+		return nil, err
+	}
+
+	return tokens, err
+}
+
+// tokenKind returns the kind of the emitted token based on its raw text.
+// Assignments are detected by the caller.
+func (p *parseState) tokenKind(raw string) TokenKind {
+	switch {
+	case raw == "":
+		return TokenWord
+	case slices.Contains(p.operators, raw):
+		return TokenOperator
+	case p.keepSep && strings.IndexFunc(raw, func(char rune) bool { return !p.isSeparator(char) }) == -1:
+		return TokenSeparator
+	case p.stripComments && strings.HasPrefix(raw, string(p.commentChar)):
+		return TokenComment
+	}
+
+	return TokenWord
+}
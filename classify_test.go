@@ -0,0 +1,56 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	str := `A=1 B="x y" ls -l | C=2 wc -l # count files`
+	tokens, err := shelltoken.Classify(str, shelltoken.Whitespace, shelltoken.SplitOperators|shelltoken.SplitStripComments)
+	require.NoError(t, err)
+	assert.Equal(t, []shelltoken.ClassifiedToken{
+		{Value: "A=1", Kind: shelltoken.TokenAssignment, Start: 0, End: 3},
+		{Value: "B=x y", Kind: shelltoken.TokenAssignment, Start: 4, End: 11},
+		{Value: "ls", Kind: shelltoken.TokenWord, Start: 12, End: 14},
+		{Value: "-l", Kind: shelltoken.TokenWord, Start: 15, End: 17},
+		{Value: "|", Kind: shelltoken.TokenOperator, Start: 18, End: 19},
+		{Value: "C=2", Kind: shelltoken.TokenAssignment, Start: 20, End: 23},
+		{Value: "wc", Kind: shelltoken.TokenWord, Start: 24, End: 26},
+		{Value: "-l", Kind: shelltoken.TokenWord, Start: 27, End: 29},
+		{Value: "# count files", Kind: shelltoken.TokenComment, Start: 30, End: 43},
+	}, tokens)
+
+	tokens, err = shelltoken.Classify(`ls  "|" x=1 '#'`, shelltoken.Whitespace, shelltoken.SplitKeepSeparator|shelltoken.SplitOperators|shelltoken.SplitStripComments)
+	require.NoError(t, err)
+	assert.Equal(t, []shelltoken.ClassifiedToken{
+		{Value: "ls", Kind: shelltoken.TokenWord, Start: 0, End: 2},
+		{Value: " ", Kind: shelltoken.TokenSeparator, Start: 2, End: 3},
+		{Value: " ", Kind: shelltoken.TokenSeparator, Start: 3, End: 4},
+		{Value: "|", Kind: shelltoken.TokenWord, Start: 4, End: 7},
+		{Value: " ", Kind: shelltoken.TokenSeparator, Start: 7, End: 8},
+		{Value: "x=1", Kind: shelltoken.TokenWord, Start: 8, End: 11},
+		{Value: " ", Kind: shelltoken.TokenSeparator, Start: 11, End: 12},
+		{Value: "#", Kind: shelltoken.TokenWord, Start: 12, End: 15},
+	}, tokens)
+
+	// without the options operators and comments are words
+	tokens, err = shelltoken.Classify("a | b #c", shelltoken.Whitespace)
+	require.NoError(t, err)
+
+	kinds := []shelltoken.TokenKind{}
+	for _, token := range tokens {
+		kinds = append(kinds, token.Kind)
+	}
+
+	assert.Equal(t, []shelltoken.TokenKind{shelltoken.TokenWord, shelltoken.TokenWord, shelltoken.TokenWord, shelltoken.TokenWord}, kinds)
+
+	_, err = shelltoken.Classify(`a "b`, shelltoken.Whitespace)
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+
+	assert.Equal(t, "Assignment", shelltoken.TokenAssignment.String())
+	assert.Equal(t, "TokenKind(9)", shelltoken.TokenKind(9).String())
+}
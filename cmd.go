@@ -0,0 +1,37 @@
+package shelltoken
+
+// cmdOperators are the metacharacters of cmd.exe returned as separate tokens by SplitCmd.
+var cmdOperators = []string{"&&", "||", "&", "|", "(", ")", ">>", "<", ">"}
+
+// SplitCmd will tokenize a string the way cmd.exe parses a command line.
+// It uses
+// - separator: " \t\n\r".
+// - escape character: "^", a caret escapes the next character outside of double quotes
+// and joins lines if followed by a newline.
+// - double quotes: group words, carets are literal inside. Single quotes are regular characters.
+// - operators: "&&", "||", "&", "|", "(", ")", ">>", "<" and ">" are returned as separate
+// tokens unless they are escaped or quoted.
+// Variable references like %PATH% are kept verbatim, cmd.exe expands them before parsing.
+// Unlike SplitWindows, this targets the cmd shell syntax instead of the argv of a process.
+func SplitCmd(str string) (argv []string, err error) {
+	cfg := NewConfig(Whitespace, SplitLineContinuation)
+	cfg.Quotes = `"`
+	cfg.EscapeChar = '^'
+	cfg.Operators = cmdOperators
+
+	pst := newParseState(&cfg)
+	pst.cmd = true
+
+	argv = []string{}
+
+	err = pst.parse(str, func(token string, _, _ int) bool {
+		argv = append(argv, token)
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return argv, nil
+}
@@ -0,0 +1,37 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCmd(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{``, []string{}},
+		{`echo a^&b`, []string{"echo", "a&b"}},
+		{`echo a & echo b`, []string{"echo", "a", "&", "echo", "b"}},
+		{`dir&&echo ok||echo fail`, []string{"dir", "&&", "echo", "ok", "||", "echo", "fail"}},
+		{`type a.txt|find "x y">>out.log 2>err.log`, []string{"type", "a.txt", "|", "find", "x y", ">>", "out.log", "2", ">", "err.log"}},
+		{`if exist a (echo yes) else (echo no)`, []string{"if", "exist", "a", "(", "echo", "yes", ")", "else", "(", "echo", "no", ")"}},
+		{`echo "a & b^" ^"c d^"`, []string{"echo", "a & b^", `"c`, `d"`}},
+		{`echo ^^ ^<tag^> 'a b'`, []string{"echo", "^", "<tag>", "'a", "b'"}},
+		{`set PATH=%PATH%;C:\bin`, []string{"set", `PATH=%PATH%;C:\bin`}},
+		{"echo a^\nb c", []string{"echo", "ab", "c"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitCmd(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "SplitCmd: %s -> %#v", tst.in, argv)
+	}
+
+	argv, err := shelltoken.SplitCmd(`echo "a`)
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+	assert.Nil(t, argv)
+}
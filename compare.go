@@ -0,0 +1,86 @@
+package shelltoken
+
+import (
+	"slices"
+)
+
+// EqualCommand returns true if both command lines are semantically identical.
+// Both strings are tokenized with SplitLinux, so the comparison is done on the
+// resolved env and argv values. Different ways of quoting or escaping the same
+// argument like \a, 'a' and "a" compare equal.
+// Command lines consisting only of assignments are compared by their env.
+// Returns an error if either command line cannot be parsed.
+func EqualCommand(cmd1, cmd2 string) (bool, error) {
+	env1, argv1, err := SplitLinux(cmd1)
+	if err != nil {
+		return false, err
+	}
+
+	env2, argv2, err := SplitLinux(cmd2)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Equal(env1, env2) && slices.Equal(argv1, argv2), nil
+}
+
+// CompareEscaping tokenizes str with the linux and the windows rules (see
+// SplitLinux and SplitWindows) and reports whether the resulting token lists
+// differ. This helps to spot command lines which behave differently on each
+// platform, ex.: because of backslashes.
+// The token lists contain the env assignments as well.
+func CompareEscaping(str string) (linux, windows []string, differ bool, err error) {
+	linux, err = SplitQuotes(str, Whitespace, linuxOptions)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	windows, err = SplitQuotes(str, Whitespace, windowsOptions)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	return linux, windows, !slices.Equal(linux, windows), nil
+}
+
+// AuditView returns the raw command line along with a normalized version for
+// audit logs. The normalized version is the command line tokenized with
+// SplitLinux and joined again with minimal quoting, see Normalize.
+// suspicious is true if a token is written differently in both versions, which
+// indicates unusual quoting or escaping in the raw command line. Differences in
+// the whitespace between tokens are not suspicious.
+func AuditView(str string) (raw, normalized string, suspicious bool, err error) {
+	normalized, err = Normalize(str)
+	if err != nil {
+		return str, "", false, err
+	}
+
+	rawWords, err := splitWords(str)
+	if err != nil {
+		return str, "", false, err
+	}
+
+	normalizedWords, err := splitWords(normalized)
+	if err != nil {
+		return str, "", false, err
+	}
+
+	return str, normalized, !slices.Equal(rawWords, normalizedWords), nil
+}
+
+// splitWords returns the tokens of str as written in str, including quotes and escapes.
+func splitWords(str string) ([]string, error) {
+	cfg := NewConfig(Whitespace, linuxOptions)
+
+	tokens, err := splitTokens(str, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	words := make([]string, len(tokens))
+	for i, token := range tokens {
+		words[i] = str[token.Start:token.End]
+	}
+
+	return words, nil
+}
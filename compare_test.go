@@ -0,0 +1,107 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEqualCommand(t *testing.T) {
+	equivalents := [][]string{
+		{`a`, `\a`, `'a'`, `"a"`, `''a`, `a""`, `"a"''`, `'a'""`},
+		{`echo a b`, `echo 'a' "b"`, `"echo" \a  b`, `  echo a    b  `, "echo\ta\nb"},
+		{`echo 'a b'`, `echo "a b"`, `echo a\ b`, `echo a' 'b`, `echo "a"' '\b`},
+		{`A=1 B='x y' cmd`, `A="1" B=x\ y cmd`, `'A=1' "B=x y" 'cmd'`},
+		{`echo "it's"`, `echo it\'s`, `echo 'it'\''s'`, `echo "it'"s`},
+		{`A=1`, `export A=1`, `A='1'`},
+	}
+
+	for _, list := range equivalents {
+		for _, cmd1 := range list {
+			for _, cmd2 := range list {
+				equal, err := shelltoken.EqualCommand(cmd1, cmd2)
+				require.NoErrorf(t, err, "error while comparing: %s <-> %s", cmd1, cmd2)
+				assert.Truef(t, equal, "commands should be equal: %s <-> %s", cmd1, cmd2)
+			}
+		}
+	}
+
+	different := [][2]string{
+		{`a`, `b`},
+		{`echo a b`, `echo "a b"`},
+		{`A=1 cmd`, `A=2 cmd`},
+		{`A=1 cmd`, `cmd A=1`},
+		{`echo ''`, `echo`},
+		{`A=1`, `B=2`},
+		{`export A=1`, `rm_all=yes`},
+		{`A=1`, ``},
+	}
+
+	for _, tst := range different {
+		equal, err := shelltoken.EqualCommand(tst[0], tst[1])
+		require.NoErrorf(t, err, "error while comparing: %s <-> %s", tst[0], tst[1])
+		assert.Falsef(t, equal, "commands should differ: %s <-> %s", tst[0], tst[1])
+	}
+
+	_, err := shelltoken.EqualCommand(`echo "a`, `echo a`)
+	require.Error(t, err)
+}
+
+func TestCompareEscaping(t *testing.T) {
+	tests := []struct {
+		in      string
+		linux   []string
+		windows []string
+		differ  bool
+	}{
+		{`ls -l "a b"`, []string{"ls", "-l", "a b"}, []string{"ls", "-l", "a b"}, false},
+		{`A=1 cmd`, []string{"A=1", "cmd"}, []string{"A=1", "cmd"}, false},
+		{`C:\dir\app.exe`, []string{`C:dirapp.exe`}, []string{`C:\dir\app.exe`}, true},
+		{`echo a\ b`, []string{`echo`, `a b`}, []string{`echo`, `a\`, `b`}, true},
+		{`echo "a""b"`, []string{`echo`, `ab`}, []string{`echo`, `a"b`}, true},
+	}
+
+	for _, tst := range tests {
+		linux, windows, differ, err := shelltoken.CompareEscaping(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.linux, linux, "linux tokens of: %s", tst.in)
+		assert.Equalf(t, tst.windows, windows, "windows tokens of: %s", tst.in)
+		assert.Equalf(t, tst.differ, differ, "differ for: %s", tst.in)
+	}
+
+	_, _, _, err := shelltoken.CompareEscaping(`echo "a`)
+	require.Error(t, err)
+}
+
+func TestAuditView(t *testing.T) {
+	tests := []struct {
+		in         string
+		normalized string
+		suspicious bool
+	}{
+		{`ls -l /tmp`, `ls -l /tmp`, false},
+		{`A=1 ls 'a b'`, `A=1 ls 'a b'`, false},
+		{`ls  -l`, `ls -l`, false},
+		{"  ls\t-l\n", `ls -l`, false},
+		{`A=1`, `A=1`, false},
+		{`export A=1`, `A=1`, true},
+		{`A="1" ls`, `A=1 ls`, true},
+		{`l"s" -l`, `ls -l`, true},
+		{`\l\s "a b"`, `ls 'a b'`, true},
+		{`r''m -rf x`, `rm -rf x`, true},
+	}
+
+	for _, tst := range tests {
+		raw, normalized, suspicious, err := shelltoken.AuditView(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.in, raw, "raw of: %s", tst.in)
+		assert.Equalf(t, tst.normalized, normalized, "normalized of: %s", tst.in)
+		assert.Equalf(t, tst.suspicious, suspicious, "suspicious for: %s", tst.in)
+	}
+
+	raw, _, _, err := shelltoken.AuditView(`ls | rm`)
+	require.Error(t, err)
+	assert.Equal(t, `ls | rm`, raw)
+}
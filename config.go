@@ -0,0 +1,157 @@
+package shelltoken
+
+// Config contains the settings used to split a string.
+type Config struct {
+	// Separators contains all characters which separate tokens, ex.: Whitespace.
+	Separators string
+
+	// SeparatorFunc reports whether a rune separates tokens, ex.: unicode.IsSpace.
+	// If set, it takes precedence and Separators is ignored.
+	SeparatorFunc func(char rune) bool
+
+	// Options is a bitmask of SplitOption(s).
+	Options SplitOption
+
+	// Quotes contains all quote characters, ex.: DefaultQuotes.
+	// Each quote character only pairs with itself. A double quote behaves like a
+	// shell double quote, all other quote characters behave like single quotes,
+	// their content is taken literally.
+	Quotes string
+
+	// EscapeChar escapes the following character, ex.: '\\'.
+	// Zero disables escape processing.
+	EscapeChar rune
+
+	// RawStart and RawEnd mark a raw region, ex.: "<<<RAW" and "RAW>>>".
+	// The content of a raw region is added verbatim to the current token,
+	// no quote, escape, separator or shell character processing happens inside.
+	// Both must be set to enable raw regions.
+	RawStart string
+	RawEnd   string
+
+	// Operators are split from adjacent words and returned as separate tokens,
+	// ex.: with "|" as operator "a|b" results in "a", "|", "b".
+	// Operators are only recognized outside of quotes, the longest match wins.
+	// Unlike separators, operators are always kept and are not reported as
+	// shell characters.
+	Operators []string
+
+	// OutsideQuoteShellCharacters and DoubleQuoteShellCharacters replace the sets of
+	// shell characters outside of quotes and inside double quotes.
+	// Empty values use the package defaults with the same name.
+	OutsideQuoteShellCharacters string
+	DoubleQuoteShellCharacters  string
+
+	// MaxTokens limits the number of tokens, zero means unlimited.
+	// Exceeding the limit returns a TooManyTokensError along with the tokens parsed so far.
+	// Kept separators and operators count as tokens as well.
+	MaxTokens int
+
+	// MaxInputLen limits the length of the input in bytes, zero means unlimited.
+	// Longer inputs return an InputTooLongError without being parsed.
+	// Only the methods of Config apply the limit, SplitQuotes, Splitter and
+	// Tokenizer do not use a Config and are not limited.
+	MaxInputLen int
+
+	// CapacityHint is the expected number of tokens. The result slice is
+	// allocated with this capacity to avoid growing it while parsing.
+	CapacityHint int
+
+	// CommentChar starts a comment if SplitStripComments is set. Defaults to '#'.
+	CommentChar rune
+
+	// Lookup enables variable expansion of $VAR and ${VAR} outside of single quotes.
+	// It returns the value of the variable and whether it is defined.
+	Lookup func(name string) (string, bool)
+
+	// HomeDir resolves the home directory of user for SplitExpandTilde.
+	// An empty user refers to the current user.
+	HomeDir func(user string) (string, bool)
+}
+
+// NewConfig returns a Config using the default quotes and backslash as escape character.
+func NewConfig(sep string, options ...SplitOption) Config {
+	return Config{
+		Separators: sep,
+		Options:    combineOptions(options),
+		Quotes:     DefaultQuotes,
+		EscapeChar: '\\',
+	}
+}
+
+// WithShellCharacters returns a copy of the config using the given sets of shell
+// characters outside of quotes and inside double quotes.
+func (c Config) WithShellCharacters(outside, insideDouble string) Config {
+	c.OutsideQuoteShellCharacters = outside
+	c.DoubleQuoteShellCharacters = insideDouble
+
+	return c
+}
+
+// WithSeparatorFunc returns a copy of the config using isSep instead of
+// Separators to detect separators.
+func (c Config) WithSeparatorFunc(isSep func(char rune) bool) Config {
+	c.SeparatorFunc = isSep
+
+	return c
+}
+
+// WithQuoteSet returns a copy of the config using the given quote characters, ex.: DefaultQuotes+"`".
+// Each quote character pairs with itself, other quote characters are literal inside.
+func (c Config) WithQuoteSet(quotes string) Config {
+	c.Quotes = quotes
+
+	return c
+}
+
+// WithEscapeChar returns a copy of the config using char as escape character
+// instead of the backslash, ex.: '^' for cmd.exe. Zero disables escaping.
+// The special rules for backslashes inside double quotes only apply to the backslash.
+func (c Config) WithEscapeChar(char rune) Config {
+	c.EscapeChar = char
+
+	return c
+}
+
+// WithCapacityHint returns a copy of the config pre-allocating the result for num tokens.
+func (c Config) WithCapacityHint(num int) Config {
+	c.CapacityHint = num
+
+	return c
+}
+
+// WithMaxInputLen returns a copy of the config limiting the input to num bytes.
+func (c Config) WithMaxInputLen(num int) Config {
+	c.MaxInputLen = num
+
+	return c
+}
+
+// Split will tokenize text into chunks honoring quotes using the settings from the config.
+// An unsuccessful parse will return an error. The error will be either
+// UnbalancedQuotesError or ShellCharactersFoundError.
+// A TooManyTokensError is returned along with the tokens parsed so far.
+func (c Config) Split(str string) (argv []string, err error) {
+	if c.MaxInputLen > 0 && len(str) > c.MaxInputLen {
+		return nil, &InputTooLongError{Max: c.MaxInputLen, Len: len(str)}
+	}
+
+	argv = make([]string, 0, max(c.CapacityHint, 0))
+	pst := getParseState(&c)
+	defer putParseState(pst)
+
+	if c.Options&^plainOptions == 0 && pst.isPlain(str) {
+		return pst.splitPlain(str, argv), nil
+	}
+
+	err = pst.parse(str, func(token string, _, _ int) bool {
+		argv = append(argv, token)
+
+		return true
+	})
+	if err != nil && !pst.continueOnError(err) {
+		return nil, err
+	}
+
+	return argv, err
+}
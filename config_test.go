@@ -0,0 +1,331 @@
+package shelltoken_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigRawRegion(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{`cmd <<<RAW{"a": "b c", 'd': \e}RAW>>> x`, []string{"cmd", `{"a": "b c", 'd': \e}`, "x"}},
+		{`cmd --json=<<<RAW "x" RAW>>>`, []string{"cmd", `--json= "x" `}},
+		{`cmd <<<RAWRAW>>>`, []string{"cmd", ""}},
+		{`cmd "<<<RAW" 'RAW>>>'`, []string{"cmd", "<<<RAW", "RAW>>>"}},
+		{`cmd <<<RAW$(ls) | wc RAW>>>`, []string{"cmd", "$(ls) | wc "}},
+	}
+
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters)
+	cfg.RawStart = "<<<RAW"
+	cfg.RawEnd = "RAW>>>"
+
+	for _, tst := range tests {
+		argv, err := cfg.Split(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	argv, err := cfg.Split(`cmd <<<RAW "x"`)
+	require.EqualError(t, err, "unbalanced raw region opened at position 4")
+	assert.Nil(t, argv)
+
+	cfg.Options |= shelltoken.SplitKeepQuotes
+	argv, err = cfg.Split(`cmd <<<RAW x RAW>>>`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cmd", "<<<RAW x RAW>>>"}, argv)
+}
+
+func TestConfigOperators(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{`a|b`, []string{"a", "|", "b"}},
+		{`a || b`, []string{"a", "||", "b"}},
+		{`a|||b`, []string{"a", "||", "|", "b"}},
+		{`a>>b>c`, []string{"a", ">>", "b", ">", "c"}},
+		{`"a|b" 'a>b'`, []string{"a|b", "a>b"}},
+		{`|a|`, []string{"|", "a", "|"}},
+	}
+
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters)
+	cfg.Operators = []string{"|", "||", ">", ">>"}
+
+	for _, tst := range tests {
+		argv, err := cfg.Split(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	_, err := cfg.Split(`a|b;c`)
+	require.Error(t, err)
+
+	cfg.Options = shelltoken.SplitRejectMidWordQuotes
+	argv, err := cfg.Split(`"a"|'b'`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "|", "b"}, argv)
+}
+
+func TestConfigQuotesAndEscapeChar(t *testing.T) {
+	tests := []struct {
+		quotes string
+		escape rune
+		in     string
+		res    []string
+	}{
+		{"`", '^', "a `b c` d", []string{"a", "b c", "d"}},
+		{"`", '^', `a "b c" 'd'`, []string{"a", `"b`, `c"`, "'d'"}},
+		{"`", '^', "a ^`b^ c `x^y'\"`", []string{"a", "`b c", "x^y'\""}},
+		{"`", 0, `a\ b \"c`, []string{`a\`, "b", `\"c`}},
+		{"`'", 0, "`a'b` 'c`d'", []string{"a'b", "c`d"}},
+		{`"|`, '\\', `|a "b| |c\ d"|`, []string{"a \"b", `c\ d"`}},
+		{"", 0, `"a b" 'c'`, []string{`"a`, `b"`, "'c'"}},
+		{shelltoken.DefaultQuotes, '\\', `a "b\"c" 'd\'`, []string{"a", `b"c`, `d\`}},
+	}
+
+	for _, tst := range tests {
+		cfg := shelltoken.Config{Separators: shelltoken.Whitespace, Quotes: tst.quotes, EscapeChar: tst.escape}
+		argv, err := cfg.Split(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	cfg := shelltoken.Config{Separators: shelltoken.Whitespace, Quotes: "`"}
+	_, err := cfg.Split("a `b")
+	require.Error(t, err)
+}
+
+func TestConfigWithEscapeChar(t *testing.T) {
+	tests := []struct {
+		in      string
+		options shelltoken.SplitOption
+		res     []string
+	}{
+		{"a^ b", shelltoken.SplitNoOptions, []string{"a b"}},
+		{`a\ b ^^`, shelltoken.SplitNoOptions, []string{`a\`, "b", "^"}},
+		{`"a^"b" 'c^ d'`, shelltoken.SplitNoOptions, []string{`a"b`, "c^ d"}},
+		{"a^ b", shelltoken.SplitKeepBackslashes, []string{"a^ b"}},
+		{"a^ b", shelltoken.SplitIgnoreBackslashes, []string{"a", "b"}},
+		{"a^ b", shelltoken.SplitIgnoreBackslashes | shelltoken.SplitKeepBackslashes, []string{"a^", "b"}},
+		{"a^\nb c\\\nd", shelltoken.SplitLineContinuation, []string{"ab", `c\`, "d"}},
+	}
+
+	for _, tst := range tests {
+		cfg := shelltoken.NewConfig(shelltoken.Whitespace, tst.options).WithEscapeChar('^')
+		argv, err := cfg.Split(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %q -> %q", tst.in, argv)
+	}
+
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace).WithEscapeChar(0)
+	argv, err := cfg.Split(`a\ b^ c`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`a\`, "b^", "c"}, argv)
+}
+
+func TestConfigMaxTokens(t *testing.T) {
+	tests := []struct {
+		in      string
+		options shelltoken.SplitOption
+		max     int
+		res     []string
+		errPos  int
+	}{
+		{"a b c", 0, 0, []string{"a", "b", "c"}, -1},
+		{"a b c", 0, 3, []string{"a", "b", "c"}, -1},
+		{"a b c", 0, 2, []string{"a", "b"}, 4},
+		{"a 'b c' d e", 0, 2, []string{"a", "b c"}, 8},
+		{"a b c", shelltoken.SplitKeepSeparator, 3, []string{"a", " ", "b"}, 3},
+		{"a  b", shelltoken.SplitKeepSeparator, 3, []string{"a", " ", " "}, 3},
+	}
+
+	for _, tst := range tests {
+		cfg := shelltoken.NewConfig(shelltoken.Whitespace, tst.options)
+		cfg.MaxTokens = tst.max
+		argv, err := cfg.Split(tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+
+		if tst.errPos == -1 {
+			require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+
+			continue
+		}
+
+		limitErr := &shelltoken.TooManyTokensError{}
+		require.ErrorAsf(t, err, &limitErr, "expected error for: %s", tst.in)
+		assert.Equal(t, tst.max, limitErr.Max)
+		assert.Equalf(t, tst.errPos, limitErr.Pos, "error position for: %s", tst.in)
+	}
+}
+
+func TestConfigWithShellCharacters(t *testing.T) {
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters).
+		WithShellCharacters(shelltoken.OutsideQuoteShellCharacters+"%", shelltoken.DoubleQuoteShellCharacters+"%")
+
+	argv, err := cfg.Split("echo foo%bar")
+	shellErr := &shelltoken.ShellCharactersFoundError{}
+	require.ErrorAs(t, err, &shellErr)
+	assert.Equal(t, '%', shellErr.Char)
+	assert.Equal(t, 8, shellErr.Offset())
+	assert.Nil(t, argv)
+
+	_, err = cfg.Split(`echo "foo%bar"`)
+	require.ErrorAs(t, err, &shellErr)
+	assert.Equal(t, 9, shellErr.Offset())
+
+	argv, err = cfg.Split(`echo 'foo%bar'`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"echo", "foo%bar"}, argv)
+
+	// allow ~ outside of quotes
+	cfg = cfg.WithShellCharacters(strings.ReplaceAll(shelltoken.OutsideQuoteShellCharacters, "~", ""), "")
+	argv, err = cfg.Split("ls ~/x %")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls", "~/x", "%"}, argv)
+
+	_, err = cfg.Split("ls ~/x | wc")
+	require.ErrorAs(t, err, &shellErr)
+	assert.Equal(t, 7, shellErr.Offset())
+}
+
+func TestConfigMaxInputLen(t *testing.T) {
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace).WithMaxInputLen(5)
+
+	argv, err := cfg.Split("a b c")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, argv)
+
+	argv, err = cfg.Split("a b cd")
+	lenErr := &shelltoken.InputTooLongError{}
+	require.ErrorAs(t, err, &lenErr)
+	assert.Equal(t, 5, lenErr.Max)
+	assert.Equal(t, 6, lenErr.Len)
+	require.EqualError(t, err, "input length 6 exceeds maximum of 5 bytes")
+	assert.Nil(t, argv)
+
+	argv, err = cfg.WithMaxInputLen(0).Split("a b cd")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "cd"}, argv)
+}
+
+func TestConfigWithSeparatorFunc(t *testing.T) {
+	tests := []struct {
+		in      string
+		ascii   []string
+		unicode []string
+	}{
+		{"a\u00A0b", []string{"a\u00A0b"}, []string{"a", "b"}},
+		{"a\u2003b c", []string{"a\u2003b", "c"}, []string{"a", "b", "c"}},
+		{"'a\u00A0b'\u2003c", []string{"a\u00A0b\u2003c"}, []string{"a\u00A0b", "c"}},
+		{"a\\\u00A0b", []string{"a\u00A0b"}, []string{"a\u00A0b"}},
+	}
+
+	for _, tst := range tests {
+		cfg := shelltoken.NewConfig(shelltoken.Whitespace)
+		argv, err := cfg.Split(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.ascii, argv, "Tokenize: %q -> %q", tst.in, argv)
+
+		argv, err = cfg.WithSeparatorFunc(unicode.IsSpace).Split(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.unicode, argv, "Tokenize: %q -> %q", tst.in, argv)
+	}
+
+	// the separator func takes precedence over the separator string
+	cfg := shelltoken.NewConfig(",", shelltoken.SplitKeepSeparator).WithSeparatorFunc(unicode.IsSpace)
+	argv, err := cfg.Split("a,b\u2003c")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a,b", "\u2003", "c"}, argv)
+}
+
+func TestConfigWithCapacityHint(t *testing.T) {
+	str := strings.Repeat("word ", 100)
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace)
+
+	argv, err := cfg.WithCapacityHint(200).Split(str)
+	require.NoError(t, err)
+	assert.Len(t, argv, 100)
+	assert.Equal(t, 200, cap(argv))
+
+	argv, err = cfg.WithCapacityHint(2).Split(str)
+	require.NoError(t, err)
+	assert.Len(t, argv, 100)
+
+	withoutHint := testing.AllocsPerRun(10, func() { _, _ = cfg.Split(str) })
+	withHint := testing.AllocsPerRun(10, func() { _, _ = cfg.WithCapacityHint(100).Split(str) })
+	assert.Less(t, withHint, withoutHint)
+}
+
+func TestConfigWithQuoteSet(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{"a `b c` d", []string{"a", "b c", "d"}},
+		{"`a \"b\" 'c'` \"d `e` 'f'\" 'g `h` \"i\"'", []string{`a "b" 'c'`, "d `e` 'f'", "g `h` \"i\""}},
+		{"x`a b`'c d'\"e f\"", []string{"xa bc de f"}},
+		{"|a `b`| \"c|d\"", []string{"a `b`", "c|d"}},
+		{"`a\\b` c", []string{"a\\b", "c"}},
+	}
+
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters).WithQuoteSet(shelltoken.DefaultQuotes + "`|")
+
+	for _, tst := range tests {
+		argv, err := cfg.Split(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	_, err := cfg.Split("a `b 'c` d'")
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+
+	// without the backtick quote it is a shell character again
+	_, err = cfg.WithQuoteSet(shelltoken.DefaultQuotes).Split("a `b c` d")
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+}
+
+func TestConfigSplitReusesState(t *testing.T) {
+	configs := []shelltoken.Config{
+		shelltoken.NewConfig(shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters),
+		shelltoken.NewConfig(",", shelltoken.SplitKeepQuotes|shelltoken.SplitKeepEmptyFields),
+		shelltoken.NewConfig(shelltoken.Whitespace, shelltoken.SplitOperators|shelltoken.SplitKeepSeparator),
+		shelltoken.NewConfig(shelltoken.Whitespace).WithEscapeChar('^').WithQuoteSet("'|"),
+	}
+	inputs := []string{
+		`a "b c" 'd e' f\ g`,
+		`a,"b,c",,d`,
+		`x 'unbalanced`,
+		`cmd a|b && c ^|d |e f|`,
+		`echo "$HOME" a;b`,
+	}
+
+	type result struct {
+		argv []string
+		err  error
+	}
+
+	expect := map[string]result{}
+
+	for i, cfg := range configs {
+		for _, in := range inputs {
+			argv, err := cfg.Split(in)
+			expect[fmt.Sprintf("%d %s", i, in)] = result{argv, err}
+		}
+	}
+
+	// the result must not depend on the previous call
+	for i := len(configs) - 1; i >= 0; i-- {
+		for j := len(inputs) - 1; j >= 0; j-- {
+			argv, err := configs[i].Split(inputs[j])
+			assert.Equalf(t, expect[fmt.Sprintf("%d %s", i, inputs[j])], result{argv, err}, "Tokenize: %s", inputs[j])
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package shelltoken
+
+import "context"
+
+// contextCheckInterval is the number of bytes parsed between two checks of the context.
+const contextCheckInterval = 4096
+
+// SplitQuotesContext works like SplitQuotes but aborts parsing with the
+// context error once ctx is done. The context is checked periodically,
+// so parsing large inputs can be cancelled.
+func SplitQuotesContext(ctx context.Context, str, sep string, options ...SplitOption) (argv []string, err error) {
+	cfg := NewConfig(sep, options...)
+	pst := newParseState(&cfg)
+	pst.ctx = ctx
+
+	argv = []string{}
+
+	err = pst.parse(str, func(token string, _, _ int) bool {
+		argv = append(argv, token)
+
+		return true
+	})
+	if err != nil && !pst.continueOnError(err) {
+		return nil, err
+	}
+
+	return argv, err
+}
+
+// checkContext returns the context error if the context is done.
+// The context is only checked every contextCheckInterval bytes.
+func (p *parseState) checkContext(pos int) error {
+	if pos < p.ctxCheck {
+		return nil
+	}
+
+	p.ctxCheck = pos + contextCheckInterval
+
+	return p.ctx.Err()
+}
@@ -0,0 +1,67 @@
+package shelltoken_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cancelAfterContext is canceled after Err has been called a number of times.
+type cancelAfterContext struct {
+	context.Context
+	calls int
+	after int
+}
+
+func (c *cancelAfterContext) Err() error {
+	c.calls++
+	if c.calls > c.after {
+		return context.Canceled
+	}
+
+	return nil
+}
+
+func TestSplitQuotesContext(t *testing.T) {
+	tests := []string{
+		"",
+		"a b c",
+		`echo "a b" 'c d' e\ f`,
+		strings.Repeat(`word "quoted text" `, 1000),
+	}
+
+	for _, str := range tests {
+		expect, err := shelltoken.SplitQuotes(str, shelltoken.Whitespace)
+		require.NoError(t, err)
+
+		argv, err := shelltoken.SplitQuotesContext(context.Background(), str, shelltoken.Whitespace)
+		require.NoErrorf(t, err, "error while parsing: %s", str)
+		assert.Equalf(t, expect, argv, "Tokenize: %v -> %v", str, argv)
+	}
+
+	str := strings.Repeat("word ", 10000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	argv, err := shelltoken.SplitQuotesContext(ctx, str, shelltoken.Whitespace)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, argv)
+
+	// the context is only checked periodically
+	counter := &cancelAfterContext{Context: context.Background(), after: 100}
+	_, err = shelltoken.SplitQuotesContext(counter, str, shelltoken.Whitespace)
+	require.NoError(t, err)
+	assert.Equal(t, 13, counter.calls)
+
+	// and aborts while parsing
+	counter = &cancelAfterContext{Context: context.Background(), after: 2}
+	argv, err = shelltoken.SplitQuotesContext(counter, str, shelltoken.Whitespace)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, argv)
+	assert.Equal(t, 3, counter.calls)
+}
@@ -0,0 +1,32 @@
+package shelltoken
+
+import "strings"
+
+// SplitQuotesString works like SplitQuotes but splits at each occurrence of
+// the literal string delim instead of a set of separator characters, ex.: "::".
+// Delimiters inside quotes or preceded by an escape character do not split.
+// With SplitKeepSeparator the whole delimiter is returned as a single token.
+// An empty delim does not split at all.
+func SplitQuotesString(str, delim string, options ...SplitOption) (argv []string, err error) {
+	cfg := NewConfig("", options...)
+	argv = []string{}
+	pst := newParseState(&cfg)
+	pst.delim = delim
+	pst.special = pst.specialCharacters()
+
+	err = pst.parse(str, func(token string, _, _ int) bool {
+		argv = append(argv, token)
+
+		return true
+	})
+	if err != nil && !pst.continueOnError(err) {
+		return nil, err
+	}
+
+	return argv, err
+}
+
+// isDelimiter returns true if an unquoted delimiter starts at pos.
+func (p *parseState) isDelimiter(pos int) bool {
+	return p.delim != "" && !p.inSingleQuotes && !p.inDoubleQuotes && strings.HasPrefix(p.str[pos:], p.delim)
+}
@@ -0,0 +1,45 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitQuotesString(t *testing.T) {
+	tests := []struct {
+		in      string
+		options shelltoken.SplitOption
+		res     []string
+	}{
+		{"", shelltoken.SplitNoOptions, []string{}},
+		{`a::"b::c"::d`, shelltoken.SplitNoOptions, []string{"a", "b::c", "d"}},
+		{`a::"b::c"::d`, shelltoken.SplitKeepSeparator, []string{"a", "::", "b::c", "::", "d"}},
+		{`a::'b::c'::d`, shelltoken.SplitKeepQuotes, []string{"a", "'b::c'", "d"}},
+		{`a b:c::d`, shelltoken.SplitNoOptions, []string{"a b:c", "d"}},
+		{`a:::b`, shelltoken.SplitNoOptions, []string{"a", ":b"}},
+		{`a\::b`, shelltoken.SplitNoOptions, []string{"a::b"}},
+		{`::a::::b::`, shelltoken.SplitNoOptions, []string{"a", "b"}},
+		{`::a::::b::`, shelltoken.SplitKeepEmptyFields, []string{"", "a", "", "b", ""}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotesString(tst.in, "::", tst.options)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	argv, err := shelltoken.SplitQuotesString(`a::"b`, "::")
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+	assert.Nil(t, argv)
+
+	argv, err = shelltoken.SplitQuotesString(`"a"::b`, "::", shelltoken.SplitRejectMidWordQuotes)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, argv)
+
+	argv, err = shelltoken.SplitQuotesString("a b::c", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a b::c"}, argv)
+}
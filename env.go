@@ -0,0 +1,183 @@
+package shelltoken
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// InvalidEnvKeyError is returned if a leading environment assignment
+// does not use a valid POSIX variable name.
+type InvalidEnvKeyError struct {
+	Key string // the invalid variable name
+	Pos int    // position of the assignment in the input string
+}
+
+func (e *InvalidEnvKeyError) Error() string {
+	return fmt.Sprintf("invalid environment variable name %q at position %d", e.Key, e.Pos)
+}
+
+// SplitStrictEnv works like SplitLinux but asserts that all leading
+// assignments are valid environment variables.
+// A command containing a "=" whose name is not a valid POSIX name
+// (letters, digits and underscores, not starting with a digit) returns
+// an InvalidEnvKeyError instead of being treated as command.
+func SplitStrictEnv(str string) (env, argv []string, err error) {
+	envTokens, argvTokens, err := SplitLinuxPos(str)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// SplitLinuxPos always returns at least one argv token
+	if key, _, ok := strings.Cut(argvTokens[0].Value, "="); ok {
+		return nil, nil, &InvalidEnvKeyError{Key: key, Pos: argvTokens[0].Start}
+	}
+
+	env = make([]string, 0, len(envTokens))
+	for _, token := range envTokens {
+		env = append(env, token.Value)
+	}
+
+	argv = make([]string, 0, len(argvTokens))
+	for _, token := range argvTokens {
+		argv = append(argv, token.Value)
+	}
+
+	return env, argv, nil
+}
+
+// isValidEnvKey returns true if key is a valid POSIX variable name.
+func isValidEnvKey(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	for i, char := range key {
+		switch {
+		case char == '_', char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z':
+		case char >= '0' && char <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// CommandRange returns the byte range of the command invocation in str.
+// The range starts at the first character of the command (argv[0]) and ends
+// after the last argument, leading environment assignments are excluded.
+// It uses the same rules as SplitLinux. If str contains no command, an empty
+// range at the end of str is returned.
+func CommandRange(str string) (start, end int, err error) {
+	_, argv, err := SplitLinuxPos(str)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// SplitLinuxPos adds an empty token at the end of str if there is no command
+	return argv[0].Start, argv[len(argv)-1].End, nil
+}
+
+// ExtractEnvFromArgvWithIndex works like ExtractEnvFromArgv and additionally
+// returns the index of each args element in the original argv list.
+// ex.: argvToTokenIndex[0] is the position of the command in argv.
+func ExtractEnvFromArgvWithIndex(argv []string) (envs, args []string, argvToTokenIndex []int) {
+	envs, args = ExtractEnvFromArgv(argv)
+
+	// args is always the tail of argv, export keywords are not part of envs
+	argvToTokenIndex = make([]int, len(args))
+	for i := range args {
+		argvToTokenIndex[i] = len(argv) - len(args) + i
+	}
+
+	return envs, args, argvToTokenIndex
+}
+
+// SplitLinuxEnviron works like SplitLinux but returns the environment assignments
+// merged into the environment of the current process, ready to be used as exec.Cmd.Env.
+// Assignments override existing variables, if a variable is assigned multiple
+// times the last assignment wins. Appending assignments ("KEY+=VALUE") append the
+// value to the existing variable or behave like a plain assignment otherwise.
+func SplitLinuxEnviron(str string) (environ, argv []string, err error) {
+	env, argv, err := SplitLinux(str)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mergeEnviron(os.Environ(), env), argv, nil
+}
+
+// mergeEnviron returns base with all assignments from env applied.
+// Existing variables are replaced in place, new variables are appended.
+func mergeEnviron(base, env []string) []string {
+	environ := make([]string, 0, len(base)+len(env))
+	index := make(map[string]int, len(base)+len(env))
+
+	for n, list := range [][]string{base, env} {
+		for _, assignment := range list {
+			key, value, _ := strings.Cut(assignment, "=")
+
+			// only assignments from env can append, base is a plain environment
+			appendValue := false
+			if name, ok := strings.CutSuffix(key, "+"); ok && n == 1 && isValidEnvKey(name) {
+				key = name
+				appendValue = true
+				assignment = key + "=" + value
+			}
+
+			if i, ok := index[key]; ok {
+				if appendValue {
+					assignment = environ[i] + value
+				}
+
+				environ[i] = assignment
+
+				continue
+			}
+
+			index[key] = len(environ)
+			environ = append(environ, assignment)
+		}
+	}
+
+	return environ
+}
+
+// checkEnvValue returns a ShellCharactersFoundError if token is a leading environment
+// assignment and its value contains shell characters. The first token which is
+// no assignment ends the leading assignments.
+func (p *parseState) checkEnvValue(token string, start, end int) error {
+	raw := p.str[start:end]
+
+	switch {
+	case strings.TrimFunc(raw, p.isSeparator) == "", token == "export":
+		// separators and export keywords do not end the leading assignments
+		return nil
+	case !isEnvAssignment(token):
+		p.envPhase = false
+
+		return nil
+	}
+
+	cfg := NewConfig(Whitespace, SplitStopOnShellCharacters).WithShellCharacters(p.outsideShell, p.doubleShell)
+	cfg.Quotes = p.quotes
+	cfg.EscapeChar = p.escapeChar
+
+	err := newParseState(&cfg).parse(raw, func(string, int, int) bool { return true })
+
+	var shellErr *ShellCharactersFoundError
+	if !errors.As(err, &shellErr) {
+		return nil
+	}
+
+	// positions are relative to the token
+	shifted := *shellErr
+	shifted.pos += start
+	shifted.runePos = utf8.RuneCountInString(p.str[:shifted.pos])
+	shifted.Line, shifted.Col = linePosition(p.str, shifted.pos)
+
+	return &shifted
+}
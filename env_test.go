@@ -0,0 +1,323 @@
+package shelltoken_test
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStrictEnv(t *testing.T) {
+	tests := []struct {
+		in  string
+		env []string
+		arg []string
+	}{
+		{"", []string{}, []string{""}},
+		{"cmd arg", []string{}, []string{"cmd", "arg"}},
+		{"A=1 _B2=2 cmd x=1", []string{"A=1", "_B2=2"}, []string{"cmd", "x=1"}},
+		{`A="1 2" cmd`, []string{"A=1 2"}, []string{"cmd"}},
+	}
+
+	for _, tst := range tests {
+		env, argv, err := shelltoken.SplitStrictEnv(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.arg, argv, "Tokenize: %v -> %v", tst.in, argv)
+		assert.Equalf(t, tst.env, env, "Tokenize env: %v -> %v", tst.in, env)
+	}
+}
+
+func TestSplitStrictEnvErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		key string
+		pos int
+	}{
+		{"2A=1 cmd", "2A", 0},
+		{"A=1 --flag=2 cmd", "--flag", 4},
+		{"A=1  'B-C=2' cmd", "B-C", 5},
+		{"=1 cmd", "", 0},
+	}
+
+	for _, tst := range tests {
+		env, argv, err := shelltoken.SplitStrictEnv(tst.in)
+		keyErr := &shelltoken.InvalidEnvKeyError{}
+		require.ErrorAsf(t, err, &keyErr, "expected invalid key error for: %s", tst.in)
+		assert.Equalf(t, tst.key, keyErr.Key, "invalid key for: %s", tst.in)
+		assert.Equalf(t, tst.pos, keyErr.Pos, "position for: %s", tst.in)
+		assert.Nil(t, argv, "argv is nil")
+		assert.Nil(t, env, "env is nil")
+	}
+}
+
+func TestCommandRange(t *testing.T) {
+	tests := []struct {
+		in    string
+		start int
+		end   int
+	}{
+		{"", 0, 0},
+		{"  ", 2, 2},
+		{"A=1", 3, 3},
+		{"ls -l", 0, 5},
+		{"  ls -l  ", 2, 7},
+		{"A=1 B='2 3' ls -l", 12, 17},
+		{`A=1 "ls" 'x y' `, 4, 14},
+		{"A=1 ö ü", 4, 9},
+	}
+
+	for _, tst := range tests {
+		start, end, err := shelltoken.CommandRange(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.start, start, "start of: %s", tst.in)
+		assert.Equalf(t, tst.end, end, "end of: %s", tst.in)
+	}
+
+	_, _, err := shelltoken.CommandRange("A=1 ls | wc")
+	require.Error(t, err)
+}
+
+func TestExtractEnvFromArgv(t *testing.T) {
+	tests := []struct {
+		in  []string
+		env []string
+		arg []string
+	}{
+		{[]string{"A=1", "--flag=2", "cmd"}, []string{"A=1"}, []string{"--flag=2", "cmd"}},
+		{[]string{"A=1", "2foo=bar", "cmd"}, []string{"A=1"}, []string{"2foo=bar", "cmd"}},
+		{[]string{"--x=y", "A=1"}, []string{}, []string{"--x=y", "A=1"}},
+		{[]string{"_A=", "B_2=a=b", "cmd"}, []string{"_A=", "B_2=a=b"}, []string{"cmd"}},
+		{[]string{"=1", "cmd"}, []string{}, []string{"=1", "cmd"}},
+		{[]string{"export", "A=1", "B=2", "cmd", "arg"}, []string{"A=1", "B=2"}, []string{"cmd", "arg"}},
+		{[]string{"export", "A=1", "export", "B=2", "cmd"}, []string{"A=1", "B=2"}, []string{"cmd"}},
+		{[]string{"A=1", "export", "B=2", "cmd", "export", "C=3"}, []string{"A=1", "B=2"}, []string{"cmd", "export", "C=3"}},
+		{[]string{"export", "PATH", "cmd"}, []string{}, []string{"export", "PATH", "cmd"}},
+		{[]string{"export"}, []string{}, []string{"export"}},
+		{[]string{"A=1"}, []string{"A=1"}, []string{}},
+		{[]string{"export", "A=1", "export", "B=2"}, []string{"A=1", "B=2"}, []string{}},
+	}
+
+	for _, tst := range tests {
+		env, argv := shelltoken.ExtractEnvFromArgv(tst.in)
+		assert.Equalf(t, tst.env, env, "env of: %v", tst.in)
+		assert.Equalf(t, tst.arg, argv, "argv of: %v", tst.in)
+	}
+
+	env, argv, err := shelltoken.SplitLinux("A=1 --flag=2 cmd")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A=1"}, env)
+	assert.Equal(t, []string{"--flag=2", "cmd"}, argv)
+
+	env, argv, err = shelltoken.SplitLinux("export A=1 B=2 cmd arg")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A=1", "B=2"}, env)
+	assert.Equal(t, []string{"cmd", "arg"}, argv)
+
+	// argv always contains at least one element
+	for _, str := range []string{"A=1", "export A=1"} {
+		env, argv, err = shelltoken.SplitLinux(str)
+		require.NoError(t, err)
+		assert.Equalf(t, []string{"A=1"}, env, "env of: %s", str)
+		assert.Equalf(t, []string{""}, argv, "argv of: %s", str)
+	}
+
+	envTokens, argvTokens, err := shelltoken.SplitLinuxPos("export A=1 export B=2 cmd")
+	require.NoError(t, err)
+	assert.Equal(t, []shelltoken.Token{{Value: "A=1", Start: 7, End: 10}, {Value: "B=2", Start: 18, End: 21}}, envTokens)
+	assert.Equal(t, []shelltoken.Token{{Value: "cmd", Start: 22, End: 25}}, argvTokens)
+
+	start, end, err := shelltoken.CommandRange("export A=1 cmd")
+	require.NoError(t, err)
+	assert.Equal(t, 11, start)
+	assert.Equal(t, 14, end)
+}
+
+func TestExtractEnvFromArgvFunc(t *testing.T) {
+	upperKey := regexp.MustCompile(`^[A-Z_][A-Z0-9_]*=`)
+	isEnv := func(arg string) bool { return upperKey.MatchString(arg) }
+
+	tests := []struct {
+		in  []string
+		env []string
+		arg []string
+	}{
+		{[]string{"A=1", "B_2=x", "cmd"}, []string{"A=1", "B_2=x"}, []string{"cmd"}},
+		{[]string{"A=1", "b=2", "cmd"}, []string{"A=1"}, []string{"b=2", "cmd"}},
+		{[]string{"a.b=1", "cmd"}, []string{}, []string{"a.b=1", "cmd"}},
+		{[]string{"export", "A=1", "export", "x=2", "cmd"}, []string{"A=1"}, []string{"export", "x=2", "cmd"}},
+		{[]string{"2A=1", "cmd"}, []string{}, []string{"2A=1", "cmd"}},
+	}
+
+	for _, tst := range tests {
+		env, argv := shelltoken.ExtractEnvFromArgvFunc(tst.in, isEnv)
+		assert.Equalf(t, tst.env, env, "env of: %v", tst.in)
+		assert.Equalf(t, tst.arg, argv, "argv of: %v", tst.in)
+	}
+
+	// the default predicate accepts lowercase names
+	env, argv := shelltoken.ExtractEnvFromArgv([]string{"A=1", "b=2", "cmd"})
+	assert.Equal(t, []string{"A=1", "b=2"}, env)
+	assert.Equal(t, []string{"cmd"}, argv)
+}
+
+func TestSplitLinuxNoEnv(t *testing.T) {
+	tests := []struct {
+		in   string
+		argv []string
+	}{
+		{"", []string{""}},
+		{"KEY=VAL", []string{"KEY=VAL"}},
+		{"A=1 B='2 3' cmd x=y", []string{"A=1", "B=2 3", "cmd", "x=y"}},
+		{"export A=1", []string{"export", "A=1"}},
+	}
+
+	for _, tst := range tests {
+		env, argv, err := shelltoken.SplitLinuxNoEnv(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, []string{}, env, "env of: %s", tst.in)
+		assert.Equalf(t, tst.argv, argv, "argv of: %s", tst.in)
+	}
+
+	_, _, err := shelltoken.SplitLinuxNoEnv("A=1 ls | wc")
+	require.Error(t, err)
+}
+
+func TestExtractEnvFromArgvWithIndex(t *testing.T) {
+	tests := []struct {
+		in    []string
+		env   []string
+		arg   []string
+		index []int
+	}{
+		{[]string{"cmd"}, []string{}, []string{"cmd"}, []int{0}},
+		{[]string{"cmd", "a", "b"}, []string{}, []string{"cmd", "a", "b"}, []int{0, 1, 2}},
+		{[]string{"A=1", "B=2", "cmd", "a", "x=y"}, []string{"A=1", "B=2"}, []string{"cmd", "a", "x=y"}, []int{2, 3, 4}},
+		{[]string{"export", "A=1", "export", "B=2", "cmd", "a"}, []string{"A=1", "B=2"}, []string{"cmd", "a"}, []int{4, 5}},
+	}
+
+	for _, tst := range tests {
+		env, argv, index := shelltoken.ExtractEnvFromArgvWithIndex(tst.in)
+		assert.Equalf(t, tst.env, env, "env of: %v", tst.in)
+		assert.Equalf(t, tst.arg, argv, "argv of: %v", tst.in)
+		assert.Equalf(t, tst.index, index, "index of: %v", tst.in)
+
+		for i := range argv {
+			assert.Equal(t, tst.in[index[i]], argv[i])
+		}
+	}
+}
+
+func TestSplitLinuxEnviron(t *testing.T) {
+	t.Setenv("SHELLTOKEN_A", "0")
+	t.Setenv("SHELLTOKEN_B", "b")
+
+	environ, argv, err := shelltoken.SplitLinuxEnviron("SHELLTOKEN_A=1 SHELLTOKEN_A=2 SHELLTOKEN_C='x y' cmd arg")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cmd", "arg"}, argv)
+
+	count := map[string]int{}
+	for _, assignment := range environ {
+		key, _, _ := strings.Cut(assignment, "=")
+		count[key]++
+	}
+
+	for key, num := range count {
+		assert.Equalf(t, 1, num, "variable %s is unique", key)
+	}
+
+	assert.Contains(t, environ, "SHELLTOKEN_A=2")
+	assert.Contains(t, environ, "SHELLTOKEN_B=b")
+	assert.Contains(t, environ, "SHELLTOKEN_C=x y")
+	assert.Len(t, environ, len(os.Environ())+1)
+
+	// assignments without command are kept
+	environ, argv, err = shelltoken.SplitLinuxEnviron("SHELLTOKEN_A=3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{""}, argv)
+	assert.Contains(t, environ, "SHELLTOKEN_A=3")
+	assert.NotContains(t, environ, "SHELLTOKEN_A=0")
+
+	_, _, err = shelltoken.SplitLinuxEnviron("A=1 cmd | wc")
+	require.Error(t, err)
+}
+
+func TestSplitLinuxEnvironAppend(t *testing.T) {
+	t.Setenv("SHELLTOKEN_PATH", "/bin")
+	t.Setenv("SHELLTOKEN_X", "x")
+
+	env, argv, err := shelltoken.SplitLinux("SHELLTOKEN_PATH+=:/x cmd")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SHELLTOKEN_PATH+=:/x"}, env)
+	assert.Equal(t, []string{"cmd"}, argv)
+
+	environ, argv, err := shelltoken.SplitLinuxEnviron("SHELLTOKEN_PATH+=:/x SHELLTOKEN_PATH+=:/y SHELLTOKEN_NEW+=a SHELLTOKEN_X=1 SHELLTOKEN_X+=2 cmd")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cmd"}, argv)
+	assert.Contains(t, environ, "SHELLTOKEN_PATH=/bin:/x:/y")
+	assert.Contains(t, environ, "SHELLTOKEN_NEW=a")
+	assert.Contains(t, environ, "SHELLTOKEN_X=12")
+	assert.Len(t, environ, len(os.Environ())+1)
+
+	for _, assignment := range environ {
+		assert.NotContainsf(t, assignment, "+=", "no append assignment left: %s", assignment)
+	}
+
+	// a "+" which is not part of the operator is no valid assignment
+	env, argv, err = shelltoken.SplitLinux("A++=1 cmd")
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, env)
+	assert.Equal(t, []string{"A++=1", "cmd"}, argv)
+
+	assert.Equal(t, "PATH+=':/a b' cmd", shelltoken.BuildCommandLine([]string{"PATH+=:/a b"}, []string{"cmd"}))
+}
+
+func TestSplitCheckEnvValues(t *testing.T) {
+	tests := []struct {
+		in   string
+		argv []string
+		pos  int
+	}{
+		{"A=1 cmd", []string{"A=1", "cmd"}, -1},
+		{"A=$(x) cmd", nil, 2},
+		{`A=1 B="a $(x)" cmd`, nil, 9},
+		{`A=1 B='a $(x)' cmd`, []string{"A=1", "B=a $(x)", "cmd"}, -1},
+		{`export A=1 export B='a|b' C=x|y cmd`, nil, 29},
+		{"A=1 cmd B=$(x) $(y)", []string{"A=1", "cmd", "B=$(x)", "$(y)"}, -1},
+		{"cmd A=$(x)", []string{"cmd", "A=$(x)"}, -1},
+		{"--opt=$(x) cmd", []string{"--opt=$(x)", "cmd"}, -1},
+		{"ö=1 Ä=`x`", []string{"ö=1", "Ä=`x`"}, -1},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitCheckEnvValues)
+		assert.Equalf(t, tst.argv, argv, "Tokenize: %s -> %q", tst.in, argv)
+
+		if tst.pos == -1 {
+			require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+
+			continue
+		}
+
+		shellErr := &shelltoken.ShellCharactersFoundError{}
+		require.ErrorAsf(t, err, &shellErr, "expected shell character error for: %s", tst.in)
+		assert.Equalf(t, tst.pos, shellErr.Offset(), "position of: %s", tst.in)
+	}
+
+	_, err := shelltoken.SplitQuotes("A=1\nB=$(x) cmd", shelltoken.Whitespace, shelltoken.SplitCheckEnvValues)
+	require.EqualError(t, err, "shell character '$' at line 2 column 3 (offset 6, command substitution)")
+
+	res, err := shelltoken.SplitResult(`A=1 B=2 cmd "$(x)"`, shelltoken.Whitespace, shelltoken.SplitCheckEnvValues)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A=1", "B=2"}, res.Env)
+
+	tokens, err := readAllTokens(shelltoken.NewTokenizer(strings.NewReader("A=1 cmd B=$(x)"), shelltoken.Whitespace, shelltoken.SplitCheckEnvValues))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A=1", "cmd", "B=$(x)"}, tokens)
+
+	_, err = readAllTokens(shelltoken.NewTokenizer(strings.NewReader(`A=1 B="$(x)" cmd`), shelltoken.Whitespace, shelltoken.SplitCheckEnvValues))
+	require.EqualError(t, err, "shell character '$' at line 1 column 8 (offset 7, command substitution)")
+}
@@ -0,0 +1,35 @@
+package shelltoken
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// ErrEmptyCommand is returned by Command if the string does not contain a command.
+var ErrEmptyCommand = errors.New("empty command")
+
+// Command splits str like SplitLinux and returns an *exec.Cmd which runs argv[0]
+// with the remaining arguments without using a shell. Leading environment
+// assignments are added to the environment of the current process.
+// Shell characters return a ShellCharactersFoundError, use SplitQuotes with
+// SplitIgnoreShellCharacters along with ExtractEnvFromArgv to build the command
+// yourself if shell characters should be passed as regular arguments.
+func Command(str string) (*exec.Cmd, error) {
+	env, argv, err := SplitLinux(str)
+	if err != nil {
+		return nil, err
+	}
+
+	// argv is empty if str only contains environment assignments
+	if len(argv) == 0 || argv[0] == "" {
+		return nil, ErrEmptyCommand
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	if len(env) > 0 {
+		cmd.Env = mergeEnviron(os.Environ(), env)
+	}
+
+	return cmd, nil
+}
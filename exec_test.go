@@ -0,0 +1,45 @@
+package shelltoken_test
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand(t *testing.T) {
+	cmd, err := shelltoken.Command(`ls -l "a b"`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls", "-l", "a b"}, cmd.Args)
+	assert.Nil(t, cmd.Env)
+
+	t.Setenv("SHELLTOKEN_TEST", "old")
+
+	cmd, err = shelltoken.Command(`SHELLTOKEN_TEST=new B='x y' env`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"env"}, cmd.Args)
+	assert.Contains(t, cmd.Env, "B=x y")
+	assert.Contains(t, cmd.Env, "SHELLTOKEN_TEST=new")
+	assert.NotContains(t, cmd.Env, "SHELLTOKEN_TEST=old")
+
+	if _, lookErr := exec.LookPath("env"); lookErr == nil {
+		out, runErr := cmd.Output()
+		require.NoError(t, runErr)
+		assert.Contains(t, string(out), "SHELLTOKEN_TEST=new\n")
+		assert.NotContains(t, string(out), "SHELLTOKEN_TEST=old\n")
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	for _, str := range []string{"", "  ", "A=1"} {
+		cmd, err := shelltoken.Command(str)
+		require.ErrorIsf(t, err, shelltoken.ErrEmptyCommand, "expected error for: %q", str)
+		assert.Nil(t, cmd)
+	}
+
+	cmd, err := shelltoken.Command("ls | wc")
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+	assert.Nil(t, cmd)
+}
@@ -0,0 +1,125 @@
+package shelltoken
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UndefinedVariableError is returned by SplitFailOnUndefinedVariables for undefined variables.
+type UndefinedVariableError struct {
+	Name string // name of the variable
+	Pos  int    // position of the "$"
+}
+
+func (e *UndefinedVariableError) Error() string {
+	return fmt.Sprintf("undefined variable %q at position %d", e.Name, e.Pos)
+}
+
+// SplitExpand will tokenize text like SplitQuotes and expands $VAR and ${VAR}
+// in unquoted and double quoted text using lookup. Variables in single quotes are
+// never expanded. Undefined variables expand to an empty string unless
+// SplitFailOnUndefinedVariables is set. Expanded values are not split any further.
+func SplitExpand(str, sep string, lookup func(name string) (string, bool), options ...SplitOption) ([]string, error) {
+	cfg := NewConfig(sep, options...)
+	cfg.Lookup = lookup
+
+	return cfg.Split(str)
+}
+
+// expandVariable adds the value of the variable starting at pos to the current token.
+// A "$" without valid variable name is added literally.
+func (p *parseState) expandVariable(char rune, pos int) error {
+	name, end := variableName(p.str, pos)
+	if name == "" {
+		p.addToken(char, pos)
+
+		return nil
+	}
+
+	value, ok := p.lookup(name)
+	if !ok && p.failUndefined {
+		return &UndefinedVariableError{Name: name, Pos: pos}
+	}
+
+	p.markToken(pos)
+
+	if value != "" {
+		p.hasToken = true
+		p.token.WriteString(value)
+	}
+
+	p.skip = end
+
+	return nil
+}
+
+// variableName returns the name of the variable starting with the "$" at pos
+// and the position after the variable. Returns an empty name if there is no valid variable.
+func variableName(str string, pos int) (name string, end int) {
+	start := pos + 1
+	if strings.HasPrefix(str[start:], "{") {
+		length := strings.IndexByte(str[start:], '}')
+		if length == -1 || !isValidEnvKey(str[start+1:start+length]) {
+			return "", 0
+		}
+
+		return str[start+1 : start+length], start + length + 1
+	}
+
+	end = start
+	for end < len(str) && isVariableChar(str[end], end == start) {
+		end++
+	}
+
+	return str[start:end], end
+}
+
+// isVariableChar returns true if char may be used in a variable name.
+func isVariableChar(char byte, first bool) bool {
+	switch {
+	case char == '_', char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z':
+		return true
+	case char >= '0' && char <= '9':
+		return !first
+	}
+
+	return false
+}
+
+// isTildePrefix returns true if char is an unquoted "~" starting a new token.
+func (p *parseState) isTildePrefix(char rune, pos int) bool {
+	return p.expandTilde && char == '~' && p.tokenStart == -1 && !p.inSingleQuotes && !p.inDoubleQuotes
+}
+
+// expandTildePrefix replaces the tilde prefix starting at pos with the home directory.
+// The prefix is added literally if it contains other characters than a user name or
+// if the home directory cannot be resolved.
+func (p *parseState) expandTildePrefix(char rune, pos int) {
+	end := pos + 1
+	for end < len(p.str) && p.str[end] != '/' && !p.isSeparator(rune(p.str[end])) {
+		if !isUserNameChar(p.str[end]) {
+			p.addToken(char, pos)
+
+			return
+		}
+
+		end++
+	}
+
+	home, ok := p.homeDir(p.str[pos+1 : end])
+	if !ok {
+		p.addToken(char, pos)
+
+		return
+	}
+
+	p.markToken(pos)
+	p.hasToken = true
+	p.token.WriteString(home)
+	p.skip = end
+}
+
+// isUserNameChar returns true if char may be used in a user name of a tilde prefix.
+func isUserNameChar(char byte) bool {
+	return isVariableChar(char, false) || char == '.' || char == '-'
+}
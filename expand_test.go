@@ -0,0 +1,93 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLookup(name string) (string, bool) {
+	env := map[string]string{
+		"HOME":  "/home/user",
+		"SPACE": "a b",
+		"EMPTY": "",
+		"_x1":   "x",
+	}
+	val, ok := env[name]
+
+	return val, ok
+}
+
+func TestSplitExpand(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{`echo $HOME`, []string{"echo", "/home/user"}},
+		{`echo ${HOME}/bin`, []string{"echo", "/home/user/bin"}},
+		{`echo "$HOME/bin" '$HOME'`, []string{"echo", "/home/user/bin", "$HOME"}},
+		{`echo $SPACE "$SPACE"`, []string{"echo", "a b", "a b"}},
+		{`echo $_x1-$_x1.$HOMEx`, []string{"echo", "x-x."}},
+		{`echo $EMPTY $UNDEFINED "$EMPTY"`, []string{"echo", ""}},
+		{`echo \$HOME "\$HOME"`, []string{"echo", "$HOME", "$HOME"}},
+		{`echo $ $1 ${1} ${HOME`, []string{"echo", "$", "$1", "${1}", "${HOME"}},
+		{`echo "${HOME}"'${HOME}'`, []string{"echo", "/home/user${HOME}"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitExpand(tst.in, shelltoken.Whitespace, testLookup)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+}
+
+func TestSplitExpandErrors(t *testing.T) {
+	argv, err := shelltoken.SplitExpand(`echo $HOME $UNDEFINED`, shelltoken.Whitespace, testLookup, shelltoken.SplitFailOnUndefinedVariables)
+	undefErr := &shelltoken.UndefinedVariableError{}
+	require.ErrorAs(t, err, &undefErr)
+	assert.Equal(t, "UNDEFINED", undefErr.Name)
+	assert.Equal(t, 11, undefErr.Pos)
+	assert.Nil(t, argv)
+
+	argv, err = shelltoken.SplitExpand(`echo $HOME ${EMPTY}`, shelltoken.Whitespace, testLookup, shelltoken.SplitFailOnUndefinedVariables|shelltoken.SplitStopOnShellCharacters)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"echo", "/home/user"}, argv)
+
+	_, err = shelltoken.SplitExpand(`echo $HOME $(id)`, shelltoken.Whitespace, testLookup, shelltoken.SplitStopOnShellCharacters)
+	require.Error(t, err)
+}
+
+func TestSplitExpandTilde(t *testing.T) {
+	homeDir := func(user string) (string, bool) {
+		switch user {
+		case "":
+			return "/home/me", true
+		case "bob":
+			return "/home/bob", true
+		}
+
+		return "", false
+	}
+
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{`ls ~`, []string{"ls", "/home/me"}},
+		{`ls ~/x`, []string{"ls", "/home/me/x"}},
+		{`ls ~bob/x ~bob`, []string{"ls", "/home/bob/x", "/home/bob"}},
+		{`ls "~/x" '~/x' \~/x`, []string{"ls", "~/x", "~/x", "~/x"}},
+		{`ls a~/x ""~/x`, []string{"ls", "a~/x", "~/x"}},
+		{`ls ~alice/x ~bob"/x" ~/"a b"`, []string{"ls", "~alice/x", "~bob/x", "/home/me/a b"}},
+	}
+
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace, shelltoken.SplitExpandTilde)
+	cfg.HomeDir = homeDir
+	for _, tst := range tests {
+		argv, err := cfg.Split(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+}
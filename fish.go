@@ -0,0 +1,32 @@
+package shelltoken
+
+import (
+	"strings"
+)
+
+// SplitFish will tokenize a string the way the fish shell does it.
+// It uses
+// - separator: " \t\n\r".
+// - single quotes: literal except for escaped single quotes and backslashes.
+// - double quotes: a backslash only escapes double quotes, "$", backslashes and newlines.
+// - outside of quotes a backslash escapes any character.
+// - a backslash followed by a newline joins both lines.
+// returns error if shell characters were found, including "()" command substitutions.
+func SplitFish(str string) (argv []string, err error) {
+	cfg := NewConfig(Whitespace, SplitStopOnShellCharacters|SplitLineContinuation)
+	pst := newParseState(&cfg)
+	pst.fish = true
+
+	argv = []string{}
+
+	err = pst.parse(strings.TrimSpace(str), func(token string, _, _ int) bool {
+		argv = append(argv, token)
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return argv, nil
+}
@@ -0,0 +1,47 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitFish(t *testing.T) {
+	tests := []struct {
+		in    string
+		fish  []string
+		linux []string
+	}{
+		{`echo 'a\b'`, []string{"echo", `a\b`}, []string{"echo", `a\b`}},
+		{`echo 'a\'b'`, []string{"echo", `a'b`}, nil},
+		{`echo 'a\\b'`, []string{"echo", `a\b`}, []string{"echo", `a\\b`}},
+		{`echo "a\$b"`, []string{"echo", `a$b`}, nil},
+		{`echo "a\"b" "a\\b" "a\nb"`, []string{"echo", `a"b`, `a\b`, `a\nb`}, []string{"echo", `a"b`, `a\b`, `a\nb`}},
+		{`echo a\ b \'`, []string{"echo", "a b", "'"}, []string{"echo", "a b", "'"}},
+		{"echo a\\\nb", []string{"echo", "ab"}, []string{"echo", "a\nb"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitFish(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.fish, argv, "SplitFish: %v -> %v", tst.in, argv)
+
+		_, argv, err = shelltoken.SplitLinux(tst.in)
+		if tst.linux == nil {
+			require.Errorf(t, err, "expected error for SplitLinux: %s", tst.in)
+
+			continue
+		}
+
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.linux, argv, "SplitLinux: %v -> %v", tst.in, argv)
+	}
+
+	for _, str := range []string{"echo (ls)", "echo $HOME", `echo "$HOME"`, "echo 'a"} {
+		argv, err := shelltoken.SplitFish(str)
+		require.Errorf(t, err, "expected error for: %s", str)
+		assert.Nil(t, argv)
+	}
+}
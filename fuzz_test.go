@@ -0,0 +1,43 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+)
+
+func FuzzSplitQuotes(f *testing.F) {
+	seeds := []string{
+		"",
+		"a b c",
+		`echo "a b" 'c d' e\ f`,
+		`trailing\`,
+		`"trailing\`,
+		"ä\\",
+		"\"ä\\",
+		"$(cmd `x`) <(y) ${A:-b} ~/x",
+		"a\r\nb # comment\n\\\nc",
+		"'it\\'s' \"\"\"\" $'\\x41'",
+		"\xff\xfe\\\xff",
+		"a\\\xff",
+	}
+	for _, seed := range seeds {
+		f.Add(seed, uint64(0))
+		f.Add(seed, ^uint64(0))
+		f.Add(seed, uint64(shelltoken.SplitStopOnShellCharacters))
+	}
+
+	f.Fuzz(func(t *testing.T, str string, options uint64) {
+		argv, err := shelltoken.SplitQuotes(str, shelltoken.Whitespace, shelltoken.SplitOption(options))
+		if err == nil && argv == nil {
+			t.Errorf("SplitQuotes(%q, %b) returned neither tokens nor an error", str, options)
+		}
+
+		_, spans, _ := shelltoken.SplitWithSpans(str, shelltoken.Whitespace, shelltoken.SplitOption(options))
+		for _, span := range spans {
+			if span.Start < 0 || span.Start > span.End || span.End > len(str) {
+				t.Errorf("SplitWithSpans(%q, %b) returned span out of range: %v", str, options, span)
+			}
+		}
+	})
+}
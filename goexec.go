@@ -0,0 +1,159 @@
+package shelltoken
+
+import (
+	"strings"
+)
+
+// SplitGoExecWindows will tokenize a string the way the go runtime does it on windows.
+// It follows the rules of os.commandLineToArgv which is used to
+// parse the command line of windows processes:
+// - arguments are separated by spaces and tabs.
+// - 2n backslashes followed by a double quote produce n backslashes and toggle quoting.
+// - 2n+1 backslashes followed by a double quote produce n backslashes and a literal quote.
+// - two double quotes within a quoted section produce a literal quote.
+// - backslashes not followed by a double quote are literal.
+// Unbalanced quotes are not an error, the quoted section simply ends with the string.
+func SplitGoExecWindows(str string) (argv []string) {
+	argv = []string{}
+
+	for str != "" {
+		if str[0] == ' ' || str[0] == '\t' {
+			str = str[1:]
+
+			continue
+		}
+
+		var arg string
+		arg, str = nextGoExecWindowsArg(str)
+		argv = append(argv, arg)
+	}
+
+	return argv
+}
+
+// JoinGoExecWindows joins argv into a single command line the way the go runtime
+// does it on windows when starting a process (syscall.EscapeArg).
+func JoinGoExecWindows(argv []string) string {
+	cmdLine := strings.Builder{}
+
+	for i, arg := range argv {
+		if i > 0 {
+			cmdLine.WriteByte(' ')
+		}
+
+		escapeGoExecWindowsArg(&cmdLine, arg)
+	}
+
+	return cmdLine.String()
+}
+
+// nextGoExecWindowsArg returns the next argument and the remaining command line.
+func nextGoExecWindowsArg(str string) (arg, rest string) {
+	token := strings.Builder{}
+	inQuotes := false
+	slashes := 0
+
+	for ; str != ""; str = str[1:] {
+		char := str[0]
+		switch char {
+		case ' ', '\t':
+			if !inQuotes {
+				writeBackslashes(&token, slashes)
+
+				return token.String(), str[1:]
+			}
+		case '"':
+			writeBackslashes(&token, slashes/2)
+
+			if slashes%2 == 0 {
+				// two double quotes inside quotes produce a literal quote
+				if inQuotes && len(str) > 1 && str[1] == '"' {
+					token.WriteByte(char)
+
+					str = str[1:]
+				}
+
+				inQuotes = !inQuotes
+			} else {
+				token.WriteByte(char)
+			}
+
+			slashes = 0
+
+			continue
+		case '\\':
+			slashes++
+
+			continue
+		}
+
+		writeBackslashes(&token, slashes)
+		slashes = 0
+
+		token.WriteByte(char)
+	}
+
+	writeBackslashes(&token, slashes)
+
+	return token.String(), ""
+}
+
+// escapeGoExecWindowsArg writes the escaped argument just like syscall.EscapeArg.
+func escapeGoExecWindowsArg(cmdLine *strings.Builder, arg string) {
+	if arg == "" {
+		cmdLine.WriteString(`""`)
+
+		return
+	}
+
+	needsBackslash := strings.ContainsAny(arg, `"\`)
+	hasSpace := strings.ContainsAny(arg, " \t")
+
+	switch {
+	case !needsBackslash && !hasSpace:
+		cmdLine.WriteString(arg)
+
+		return
+	case !needsBackslash:
+		cmdLine.WriteByte('"')
+		cmdLine.WriteString(arg)
+		cmdLine.WriteByte('"')
+
+		return
+	}
+
+	if hasSpace {
+		cmdLine.WriteByte('"')
+	}
+
+	slashes := 0
+
+	for i := 0; i < len(arg); i++ {
+		char := arg[i]
+		switch char {
+		case '\\':
+			slashes++
+		case '"':
+			// double all preceding backslashes and escape the quote
+			writeBackslashes(cmdLine, slashes+1)
+
+			slashes = 0
+		default:
+			slashes = 0
+		}
+
+		cmdLine.WriteByte(char)
+	}
+
+	if hasSpace {
+		// trailing backslashes must be doubled before the closing quote
+		writeBackslashes(cmdLine, slashes)
+		cmdLine.WriteByte('"')
+	}
+}
+
+func writeBackslashes(token *strings.Builder, num int) {
+	for ; num > 0; num-- {
+		token.WriteByte('\\')
+	}
+}
@@ -0,0 +1,74 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinGoExecWindows(t *testing.T) {
+	tests := []struct {
+		in  []string
+		res string
+	}{
+		{[]string{}, ``},
+		{[]string{""}, `""`},
+		{[]string{"a", "b"}, `a b`},
+		{[]string{"a b"}, `"a b"`},
+		{[]string{"a\tb"}, "\"a\tb\""},
+		{[]string{`a"b`}, `a\"b`},
+		{[]string{`a\b`}, `a\b`},
+		{[]string{`a\`}, `a\`},
+		{[]string{`a b\`}, `"a b\\"`},
+		{[]string{`a\"b`}, `a\\\"b`},
+		{[]string{`a b\\`}, `"a b\\\\"`},
+		{[]string{`\\server\share`}, `\\server\share`},
+		{[]string{`C:\Program Files\`, `-x`}, `"C:\Program Files\\" -x`},
+		{[]string{`say "hi"`}, `"say \"hi\""`},
+	}
+
+	for _, tst := range tests {
+		cmdLine := shelltoken.JoinGoExecWindows(tst.in)
+		assert.Equalf(t, tst.res, cmdLine, "JoinGoExecWindows: %#v -> %s", tst.in, cmdLine)
+	}
+}
+
+func TestSplitGoExecWindows(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{``, []string{}},
+		{`  a  b `, []string{"a", "b"}},
+		{`""`, []string{""}},
+		{`a\b`, []string{`a\b`}},
+		{`a\\b`, []string{`a\\b`}},
+		{`a\"b`, []string{`a"b`}},
+		{`a\\"b c"`, []string{`a\b c`}},
+		{`a\\\"b`, []string{`a\"b`}},
+		{`"a""b"`, []string{`a"b`}},
+		{`"a b`, []string{`a b`}},
+		{`"C:\Program Files\\" -x`, []string{`C:\Program Files\`, `-x`}},
+		{"a\tb", []string{"a", "b"}},
+	}
+
+	for _, tst := range tests {
+		argv := shelltoken.SplitGoExecWindows(tst.in)
+		assert.Equalf(t, tst.res, argv, "SplitGoExecWindows: %s -> %#v", tst.in, argv)
+	}
+}
+
+func TestGoExecWindowsRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{"cmd.exe", "/c", "echo"},
+		{`C:\Program Files\app.exe`, `--path=C:\dir with space\`},
+		{`a"b`, `a\"b`, `"quoted"`, `\\`, ``},
+		{`trailing\\`, `trailing space\\ `},
+	}
+
+	for _, argv := range tests {
+		res := shelltoken.SplitGoExecWindows(shelltoken.JoinGoExecWindows(argv))
+		assert.Equalf(t, argv, res, "round trip: %#v -> %#v", argv, res)
+	}
+}
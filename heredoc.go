@@ -0,0 +1,182 @@
+package shelltoken
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// HeredocError is returned by SplitHeredoc if a "<<" is not followed by a delimiter word.
+type HeredocError struct {
+	Pos int // position of the "<<"
+}
+
+func (e *HeredocError) Error() string {
+	return fmt.Sprintf("missing heredoc delimiter at position %d", e.Pos)
+}
+
+// heredoc is a here-document whose body starts after the next newline.
+type heredoc struct {
+	delim     string
+	stripTabs bool // "<<-" removes leading tabs from all lines
+	quoted    bool // the delimiter was quoted, the body is taken literally
+}
+
+// isHeredocStart returns true if an unquoted "<<" or "<<-" starts at pos.
+// Here-strings ("<<<") are not here-documents.
+func (p *parseState) isHeredocStart(char rune, pos int) bool {
+	if !p.heredoc || char != '<' || p.inSingleQuotes || p.inDoubleQuotes {
+		return false
+	}
+
+	if pos > 0 && p.str[pos-1] == '<' {
+		return false
+	}
+
+	return strings.HasPrefix(p.str[pos:], "<<") && !strings.HasPrefix(p.str[pos:], "<<<")
+}
+
+// addHeredocStart emits the redirection operator and the delimiter word starting at pos
+// and remembers the here-document until the end of the line.
+func (p *parseState) addHeredocStart(pos int) error {
+	operator := "<<"
+	if strings.HasPrefix(p.str[pos:], "<<-") {
+		operator = "<<-"
+	}
+
+	p.flushToken(pos)
+	p.emitToken(operator, pos, pos+len(operator))
+
+	start := pos + len(operator)
+	for start < len(p.str) && (p.str[start] == ' ' || p.str[start] == '\t') {
+		start++
+	}
+
+	doc := heredoc{stripTabs: operator == "<<-"}
+	delim := strings.Builder{}
+	end := start
+
+	for end < len(p.str) && !p.isSeparator(rune(p.str[end])) && !strings.ContainsRune(";|&<>()", rune(p.str[end])) {
+		switch char := p.str[end]; char {
+		case '\'', '"':
+			closing := strings.IndexByte(p.str[end+1:], char)
+			if closing == -1 {
+				return &UnbalancedQuotesError{Quote: rune(char), Pos: end}
+			}
+
+			delim.WriteString(p.str[end+1 : end+1+closing])
+			doc.quoted = true
+			end += closing + 2
+		case '\\':
+			doc.quoted = true
+			end++
+
+			if end < len(p.str) {
+				delim.WriteByte(p.str[end])
+				end++
+			}
+		default:
+			delim.WriteByte(char)
+			end++
+		}
+	}
+
+	if end == start {
+		return &HeredocError{Pos: pos}
+	}
+
+	doc.delim = delim.String()
+	p.emitToken(doc.delim, start, end)
+	p.heredocs = append(p.heredocs, doc)
+	p.skip = end
+
+	return nil
+}
+
+// addHeredocBodies emits the bodies of all pending here-documents starting at pos.
+// A body without terminating line ends with the input.
+func (p *parseState) addHeredocBodies(pos int) error {
+	for _, doc := range p.heredocs {
+		p.markToken(pos)
+		p.hasToken = true
+
+		for pos < len(p.str) {
+			lineEnd := strings.IndexByte(p.str[pos:], '\n')
+			next := pos + lineEnd + 1
+
+			if lineEnd == -1 {
+				lineEnd = len(p.str) - pos
+				next = len(p.str)
+			}
+
+			lineStart := pos
+			if doc.stripTabs {
+				for lineStart < pos+lineEnd && p.str[lineStart] == '\t' {
+					lineStart++
+				}
+			}
+
+			if p.str[lineStart:pos+lineEnd] == doc.delim {
+				p.flushToken(pos)
+				pos = next
+
+				break
+			}
+
+			if err := p.addHeredocLine(doc, lineStart, next); err != nil {
+				return err
+			}
+
+			pos = next
+		}
+
+		p.flushToken(pos)
+	}
+
+	p.heredocs = nil
+	p.skip = pos
+
+	return nil
+}
+
+// addHeredocLine adds the body line from start to end to the current token.
+// Lines of unquoted here-documents are treated like double quoted text.
+func (p *parseState) addHeredocLine(doc heredoc, start, end int) error {
+	if doc.quoted {
+		p.inSingleQuotes = true
+	} else {
+		p.inDoubleQuotes = true
+	}
+
+	defer func() {
+		p.inSingleQuotes = false
+		p.inDoubleQuotes = false
+	}()
+
+	for pos := start; pos < end; {
+		char, size := utf8.DecodeRuneInString(p.str[pos:])
+
+		switch {
+		case doc.quoted:
+		case char == '\\' && pos+1 < end && strings.ContainsRune("$`\\", rune(p.str[pos+1])):
+			p.addEscaped(rune(p.str[pos+1]), pos+1)
+			pos += 2
+
+			continue
+		case char == '$' && p.lookup != nil:
+			p.skip = pos + size
+			if err := p.expandVariable(char, pos); err != nil {
+				return err
+			}
+
+			pos = p.skip
+
+			continue
+		}
+
+		p.addToken(char, pos)
+		pos += size
+	}
+
+	return nil
+}
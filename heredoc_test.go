@@ -0,0 +1,79 @@
+package shelltoken_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitHeredoc(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{"cat <<EOF\nhello\nworld\nEOF\n", []string{"cat", "<<", "EOF", "hello\nworld\n"}},
+		{"cat <<EOF | wc -l\na b\nEOF\necho done", []string{"cat", "<<", "EOF", "|", "wc", "-l", "a b\n", "echo", "done"}},
+		{"cat << 'EOF'\n'a' \"$b\"\nEOF", []string{"cat", "<<", "EOF", "'a' \"$b\"\n"}},
+		{"cat <<-END\n\ta\n\t\tb\n\tEND\nx", []string{"cat", "<<-", "END", "a\nb\n", "x"}},
+		{"cat <<EOF\n EOF\nEOF", []string{"cat", "<<", "EOF", " EOF\n"}},
+		{"cat <<EOF\nEOF", []string{"cat", "<<", "EOF", ""}},
+		{"cat <<EOF", []string{"cat", "<<", "EOF", ""}},
+		{"cat <<EOF\nno end", []string{"cat", "<<", "EOF", "no end"}},
+		{"cat <<A <<\"B\"\na\nA\nb\nB\n", []string{"cat", "<<", "A", "<<", "B", "a\n", "b\n"}},
+		{"cat <<<word", []string{"cat", "<<<word"}},
+		{"cat '<<EOF'\nEOF", []string{"cat", "<<EOF", "EOF"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitHeredoc)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %q -> %q", tst.in, argv)
+	}
+}
+
+func TestSplitHeredocExpand(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		return strings.ToLower(name), name != "UNDEF"
+	}
+
+	argv, err := shelltoken.SplitExpand("cat <<EOF\n$A ${B} \\$C \\\\\nEOF", shelltoken.Whitespace, lookup, shelltoken.SplitHeredoc)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cat", "<<", "EOF", "a b $C \\\n"}, argv)
+
+	argv, err = shelltoken.SplitExpand("cat <<'EOF'\n$A ${B} \\$C\nEOF", shelltoken.Whitespace, lookup, shelltoken.SplitHeredoc)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cat", "<<", "EOF", "$A ${B} \\$C\n"}, argv)
+
+	_, err = shelltoken.SplitExpand("cat <<EOF\n$UNDEF\nEOF", shelltoken.Whitespace, lookup, shelltoken.SplitHeredoc|shelltoken.SplitFailOnUndefinedVariables)
+	undefErr := &shelltoken.UndefinedVariableError{}
+	require.ErrorAs(t, err, &undefErr)
+	assert.Equal(t, 10, undefErr.Pos)
+}
+
+func TestSplitHeredocErrors(t *testing.T) {
+	_, err := shelltoken.SplitQuotes("cat <<\nEOF", shelltoken.Whitespace, shelltoken.SplitHeredoc)
+	require.EqualError(t, err, "missing heredoc delimiter at position 4")
+
+	_, err = shelltoken.SplitQuotes("cat <<'EOF\nEOF", shelltoken.Whitespace, shelltoken.SplitHeredoc)
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+
+	// unquoted bodies are checked like double quoted text
+	_, err = shelltoken.SplitQuotes("cat <<EOF\n$(id) | x\nEOF", shelltoken.Whitespace, shelltoken.SplitHeredoc|shelltoken.SplitStopOnShellCharacters)
+	shellErr := &shelltoken.ShellCharactersFoundError{}
+	require.ErrorAs(t, err, &shellErr)
+	assert.Equal(t, 10, shellErr.Offset())
+
+	argv, err := shelltoken.SplitQuotes("cat <<'EOF'\n$(id) | x\nEOF", shelltoken.Whitespace, shelltoken.SplitHeredoc|shelltoken.SplitStopOnShellCharacters)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cat", "<<", "EOF", "$(id) | x\n"}, argv)
+}
+
+func TestTokenizerHeredoc(t *testing.T) {
+	input := "cat <<EOF\nhello\nEOF\necho x"
+	tokens, err := readAllTokens(shelltoken.NewTokenizer(strings.NewReader(input), shelltoken.Whitespace, shelltoken.SplitHeredoc))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cat", "<<", "EOF", "hello\n", "echo", "x"}, tokens)
+}
@@ -0,0 +1,122 @@
+package shelltoken
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteJSON tokenizes str like SplitQuotes and writes the tokens as JSON array to w.
+// The document is only written if parsing succeeds, on errors nothing is written
+// to w and the error is returned, including a TooManyTokensError.
+func WriteJSON(w io.Writer, str, sep string, options ...SplitOption) error {
+	cfg := NewConfig(sep, options...)
+	pst := newParseState(&cfg)
+
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	var encErr error
+
+	buf.WriteByte('[')
+
+	err := pst.parse(str, func(token string, _, _ int) bool {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+
+		if encErr = enc.Encode(token); encErr != nil {
+			return false
+		}
+
+		// remove trailing newline added by the encoder
+		buf.Truncate(buf.Len() - 1)
+
+		return true
+	})
+
+	switch {
+	case err != nil:
+		return err
+	case encErr != nil:
+		return encErr
+	}
+
+	buf.WriteByte(']')
+
+	_, err = buf.WriteTo(w)
+
+	return err
+}
+
+// LineError is returned by ProcessLines for lines which could not be tokenized.
+type LineError struct {
+	Line int   // line number starting at 1
+	Err  error // parse error of the line
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err.Error())
+}
+
+// Unwrap returns the parse error of the line.
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// lineErrorRecord is written by ProcessLines for lines which could not be tokenized.
+type lineErrorRecord struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ProcessLines reads r line by line, tokenizes each line like SplitQuotes and
+// writes the tokens as JSON array followed by a newline to w.
+// Lines which fail to parse are written as JSON object with the line number and
+// the error instead, ex.: {"line":3,"error":"..."}, so output line N always
+// belongs to input line N. They are reported as LineError as well after all
+// lines have been processed, multiple errors are joined with errors.Join.
+// Read and write errors stop processing immediately.
+// Line endings ("\n" or "\r\n") are not part of the tokenized line.
+func ProcessLines(r io.Reader, w io.Writer, sep string, options ...SplitOption) error {
+	reader := bufio.NewReader(r)
+	lineErrs := []error{}
+
+	for num := 1; ; num++ {
+		line, err := reader.ReadString('\n')
+
+		switch {
+		case errors.Is(err, io.EOF):
+			if line == "" {
+				return errors.Join(lineErrs...)
+			}
+		case err != nil:
+			return err
+		}
+
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		buf := bytes.Buffer{}
+		if parseErr := WriteJSON(&buf, line, sep, options...); parseErr != nil {
+			lineErrs = append(lineErrs, &LineError{Line: num, Err: parseErr})
+
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+
+			if encErr := enc.Encode(lineErrorRecord{Line: num, Error: parseErr.Error()}); encErr != nil {
+				return encErr
+			}
+		} else {
+			buf.WriteByte('\n')
+		}
+
+		if _, writeErr := buf.WriteTo(w); writeErr != nil {
+			return writeErr
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package shelltoken_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		options shelltoken.SplitOption
+	}{
+		{"", shelltoken.SplitNoOptions},
+		{"a b c", shelltoken.SplitNoOptions},
+		{`echo "a \"b\"" 'c\d' <x> & "é\tü"`, shelltoken.SplitNoOptions},
+		{"a  b", shelltoken.SplitKeepSeparator | shelltoken.SplitKeepQuotes},
+		{"\x01 \xff", shelltoken.SplitNoOptions},
+	}
+
+	for _, tst := range tests {
+		expect, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, tst.options)
+		require.NoError(t, err)
+
+		buf := bytes.Buffer{}
+		err = shelltoken.WriteJSON(&buf, tst.in, shelltoken.Whitespace, tst.options)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+
+		tokens := []string{}
+		require.NoErrorf(t, json.Unmarshal(buf.Bytes(), &tokens), "decoding: %s", buf.String())
+		assert.Equalf(t, expect, tokens, "WriteJSON: %v -> %s", tst.in, buf.String())
+	}
+
+	buf := bytes.Buffer{}
+	require.NoError(t, shelltoken.WriteJSON(&buf, `a "b c" <d>`, shelltoken.Whitespace))
+	assert.Equal(t, `["a","b c","<d>"]`, buf.String())
+
+	buf.Reset()
+	err := shelltoken.WriteJSON(&buf, `a "b c`, shelltoken.Whitespace)
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+	assert.Empty(t, buf.String())
+
+	err = shelltoken.WriteJSON(&buf, `a | b`, shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters)
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+	assert.Empty(t, buf.String())
+}
+
+func TestProcessLines(t *testing.T) {
+	input := "ls -l\r\necho \"a b\" 'c'\necho \"broken\n\ncat x | wc\nlast line"
+	out := bytes.Buffer{}
+
+	err := shelltoken.ProcessLines(strings.NewReader(input), &out, shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters)
+	require.Error(t, err)
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Equal(t, []string{
+		`["ls","-l"]`,
+		`["echo","a b","c"]`,
+		`{"line":3,"error":"unbalanced \" quote opened at position 5"}`,
+		`[]`,
+		`{"line":5,"error":"shell character '|' at line 1 column 7 (offset 6)"}`,
+		`["last","line"]`,
+	}, lines)
+
+	lineErr := &shelltoken.LineError{}
+	require.ErrorAs(t, err, &lineErr)
+	assert.Equal(t, 3, lineErr.Line)
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+	assert.Equal(t, "line 3: unbalanced \" quote opened at position 5\nline 5: shell character '|' at line 1 column 7 (offset 6)", err.Error())
+
+	out.Reset()
+	require.NoError(t, shelltoken.ProcessLines(strings.NewReader("a\nb c\n"), &out, shelltoken.Whitespace))
+	assert.Equal(t, "[\"a\"]\n[\"b\",\"c\"]\n", out.String())
+
+	out.Reset()
+	require.NoError(t, shelltoken.ProcessLines(strings.NewReader(""), &out, shelltoken.Whitespace))
+	assert.Empty(t, out.String())
+
+	err = shelltoken.ProcessLines(iotest.ErrReader(io.ErrUnexpectedEOF), &out, shelltoken.Whitespace)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
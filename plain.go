@@ -0,0 +1,60 @@
+package shelltoken
+
+import (
+	"unicode/utf8"
+)
+
+// plainOptions are the options which do not change the result for plain input
+// without quotes, escape and shell characters, see isPlain.
+const plainOptions = SplitKeepBackslashes | SplitIgnoreBackslashes | SplitKeepQuotes |
+	SplitStopOnShellCharacters | SplitContinueOnShellCharacters | SplitIgnoreShellCharacters |
+	SplitRejectMidWordQuotes | SplitDoubledQuotes | SplitFailOnUndefinedVariables |
+	SplitDecodeEscapes | SplitLineContinuation | SplitStrictPOSIX |
+	SplitKeepProcessSubstitution | SplitKeepCommandSubstitution |
+	SplitNoSingleQuotes | SplitNoDoubleQuotes | SplitEscapeInSingleQuotes |
+	SplitStrictQuotes | SplitKeepRedundantBackslashes | SplitCheckEnvValues
+
+// isPlain returns true if str can be split at separators without running the parser.
+// This is the case for ascii input without quotes, escape, shell or other special
+// characters, see specialCharacters, if no limits, operators or raw regions are configured.
+func (p *parseState) isPlain(str string) bool {
+	switch {
+	case p.maxInputLen > 0, p.maxTokens > 0, len(p.operators) > 0, p.rawStart != "", p.lookup != nil:
+		return false
+	}
+
+	for i := 0; i < len(str); i++ {
+		char := rune(str[i])
+		if char >= utf8.RuneSelf || (p.special.contains(char) && !p.sepSet.contains(char)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitPlain appends the words of plain input separated by separators to argv.
+func (p *parseState) splitPlain(str string, argv []string) []string {
+	start := -1
+
+	for i := 0; i < len(str); i++ {
+		if !p.isSeparator(rune(str[i])) {
+			if start == -1 {
+				start = i
+			}
+
+			continue
+		}
+
+		if start != -1 {
+			argv = append(argv, str[start:i])
+			start = -1
+		}
+	}
+
+	if start != -1 {
+		argv = append(argv, str[start:])
+	}
+
+	return argv
+}
@@ -0,0 +1,51 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSplitPlain(t *testing.T) {
+	tests := []struct {
+		in      string
+		sep     string
+		options shelltoken.SplitOption
+	}{
+		{"", shelltoken.Whitespace, shelltoken.SplitNoOptions},
+		{"   ", shelltoken.Whitespace, shelltoken.SplitNoOptions},
+		{"ls -l /tmp", shelltoken.Whitespace, shelltoken.SplitNoOptions},
+		{"  check_http  -H localhost\t-w 5\n-c 10  ", shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters},
+		{"a,b,,c,", ",", shelltoken.SplitKeepQuotes | shelltoken.SplitKeepBackslashes},
+		{"a=1 b=2 cmd #x", shelltoken.Whitespace, shelltoken.SplitCheckEnvValues},
+		{"a\x00b c", shelltoken.Whitespace, shelltoken.SplitNoOptions},
+		{"a\rb\r\nc", shelltoken.Whitespace, shelltoken.SplitNoOptions},
+		// not plain
+		{`a "b c"`, shelltoken.Whitespace, shelltoken.SplitNoOptions},
+		{`a\ b`, shelltoken.Whitespace, shelltoken.SplitNoOptions},
+		{"ä ö", shelltoken.Whitespace, shelltoken.SplitNoOptions},
+		{"a b", shelltoken.Whitespace, shelltoken.SplitKeepSeparator},
+		{"a  b", shelltoken.Whitespace, shelltoken.SplitKeepEmptyFields},
+		{"a\tb c", " ", shelltoken.SplitTabsAsSpaces},
+	}
+
+	for _, tst := range tests {
+		// SplitHeredoc does not change the result without "<<" but disables the plain split
+		expect, err := shelltoken.SplitQuotes(tst.in, tst.sep, tst.options|shelltoken.SplitHeredoc)
+		require.NoErrorf(t, err, "error while parsing: %q", tst.in)
+
+		argv, err := shelltoken.SplitQuotes(tst.in, tst.sep, tst.options)
+		require.NoErrorf(t, err, "error while parsing: %q", tst.in)
+		assert.Equalf(t, expect, argv, "Tokenize: %q -> %q", tst.in, argv)
+	}
+
+	argv, err := shelltoken.NewConfig(shelltoken.Whitespace).WithCapacityHint(10).Split("a b c")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, argv)
+	assert.Equal(t, 10, cap(argv))
+
+	_, err = shelltoken.NewConfig(shelltoken.Whitespace).WithMaxInputLen(3).Split("a b c")
+	require.Error(t, err)
+}
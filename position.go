@@ -0,0 +1,132 @@
+package shelltoken
+
+// Token is a parsed token along with its position in the input string.
+type Token struct {
+	Value string // the parsed value with quotes and escapes removed
+	Start int    // byte offset of the first character of the raw token
+	End   int    // byte offset one past the last character of the raw token
+}
+
+// SplitQuotesWithPositions works like SplitQuotes but returns the tokens along
+// with their byte offsets in str. The offsets cover the raw token including
+// quotes and escape characters, even if those are removed from the value.
+func SplitQuotesWithPositions(str, sep string, options ...SplitOption) ([]Token, error) {
+	cfg := NewConfig(sep, options...)
+
+	return splitTokens(str, &cfg)
+}
+
+// RawToken is a parsed token along with the exact input it was parsed from.
+type RawToken struct {
+	Value string // the parsed value with quotes and escapes removed
+	Raw   string // the raw token as written in the input including quotes and escapes
+}
+
+// SplitQuotesRaw works like SplitQuotes but returns each token along with the
+// substring of str it was parsed from, ex.: Raw "a\ b" results in Value "a b".
+func SplitQuotesRaw(str, sep string, options ...SplitOption) ([]RawToken, error) {
+	cfg := NewConfig(sep, options...)
+
+	tokens, err := splitTokens(str, &cfg)
+	if tokens == nil {
+		return nil, err
+	}
+
+	raw := make([]RawToken, len(tokens))
+	for i, token := range tokens {
+		raw[i] = RawToken{Value: token.Value, Raw: str[token.Start:token.End]}
+	}
+
+	return raw, err
+}
+
+// SplitLinuxPos works like SplitLinux but returns the env and argv tokens
+// along with their positions in str.
+func SplitLinuxPos(str string) (env, argv []Token, err error) {
+	return splitPlatformPos(str, linuxOptions)
+}
+
+// SplitWindowsPos works like SplitWindows but returns the env and argv tokens
+// along with their positions in str.
+func SplitWindowsPos(str string) (env, argv []Token, err error) {
+	return splitPlatformPos(str, windowsOptions)
+}
+
+func splitPlatformPos(str string, options SplitOption) (env, argv []Token, err error) {
+	cfg := NewConfig(Whitespace, options)
+
+	tokens, err := splitTokens(str, &cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := make([]string, len(tokens))
+	for i := range tokens {
+		values[i] = tokens[i].Value
+	}
+
+	envIndex, cmd := extractEnvIndex(values)
+
+	env = make([]Token, 0, len(envIndex))
+	for _, i := range envIndex {
+		env = append(env, tokens[i])
+	}
+
+	argv = tokens[cmd:]
+
+	if len(argv) == 0 {
+		argv = append(argv, Token{Value: "", Start: len(str), End: len(str)})
+	}
+
+	return env, argv, nil
+}
+
+// splitTokens tokenizes str and returns the tokens along with their positions.
+func splitTokens(str string, cfg *Config) (tokens []Token, err error) {
+	tokens = []Token{}
+	pst := newParseState(cfg)
+
+	err = pst.parse(str, func(token string, start, end int) bool {
+		tokens = append(tokens, Token{Value: token, Start: start, End: end})
+
+		return true
+	})
+	if err != nil && !pst.continueOnError(err) {
+		return nil, err
+	}
+
+	return tokens, err
+}
+
+// SplitDetailed works like SplitLinux and additionally returns the separators
+// of the argv elements. seps[i] contains the exact run of separator characters
+// preceding argv[i] in str. seps[0] contains the separators between the last env
+// assignment (or the start of str) and the command. Separators before and between
+// env assignments and after the last argument are not returned.
+func SplitDetailed(str string) (env, argv, seps []string, err error) {
+	envTokens, argvTokens, err := SplitLinuxPos(str)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	prevEnd := 0
+	if len(envTokens) > 0 {
+		prevEnd = envTokens[len(envTokens)-1].End
+	}
+
+	env = make([]string, 0, len(envTokens))
+	for _, token := range envTokens {
+		env = append(env, token.Value)
+	}
+
+	argv = make([]string, 0, len(argvTokens))
+	seps = make([]string, 0, len(argvTokens))
+
+	for _, token := range argvTokens {
+		argv = append(argv, token.Value)
+		seps = append(seps, str[prevEnd:token.Start])
+		prevEnd = token.End
+	}
+
+	return env, argv, seps, nil
+}
@@ -0,0 +1,129 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitLinuxPos(t *testing.T) {
+	tests := []struct {
+		in  string
+		env []shelltoken.Token
+		arg []shelltoken.Token
+	}{
+		{"", []shelltoken.Token{}, []shelltoken.Token{{"", 0, 0}}},
+		{"A=1", []shelltoken.Token{{"A=1", 0, 3}}, []shelltoken.Token{{"", 3, 3}}},
+		{" ls  -l ", []shelltoken.Token{}, []shelltoken.Token{{"ls", 1, 3}, {"-l", 5, 7}}},
+		{`A='1 2' ls "a b"c`, []shelltoken.Token{{"A=1 2", 0, 7}}, []shelltoken.Token{{"ls", 8, 10}, {"a bc", 11, 17}}},
+		{`ls \ x ö`, []shelltoken.Token{}, []shelltoken.Token{{"ls", 0, 2}, {" x", 3, 6}, {"ö", 7, 9}}},
+	}
+
+	for _, tst := range tests {
+		env, argv, err := shelltoken.SplitLinuxPos(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.env, env, "env of: %s", tst.in)
+		assert.Equalf(t, tst.arg, argv, "argv of: %s", tst.in)
+	}
+
+	_, _, err := shelltoken.SplitLinuxPos("ls | wc")
+	require.Error(t, err)
+}
+
+func TestSplitWindowsPos(t *testing.T) {
+	tests := []struct {
+		in  string
+		env []shelltoken.Token
+		arg []shelltoken.Token
+	}{
+		{`"C:\Program Files\app.exe" -x`, []shelltoken.Token{}, []shelltoken.Token{{`C:\Program Files\app.exe`, 0, 26}, {"-x", 27, 29}}},
+		{`A=1 C:\app.exe`, []shelltoken.Token{{"A=1", 0, 3}}, []shelltoken.Token{{`C:\app.exe`, 4, 14}}},
+	}
+
+	for _, tst := range tests {
+		env, argv, err := shelltoken.SplitWindowsPos(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.env, env, "env of: %s", tst.in)
+		assert.Equalf(t, tst.arg, argv, "argv of: %s", tst.in)
+	}
+}
+
+func TestSplitQuotesWithPositions(t *testing.T) {
+	tests := []struct {
+		in      string
+		options shelltoken.SplitOption
+		res     []shelltoken.Token
+	}{
+		{"", shelltoken.SplitNoOptions, []shelltoken.Token{}},
+		{`a"b"c d`, shelltoken.SplitNoOptions, []shelltoken.Token{{"abc", 0, 5}, {"d", 6, 7}}},
+		{`'x y'  "z"`, shelltoken.SplitNoOptions, []shelltoken.Token{{"x y", 0, 5}, {"z", 7, 10}}},
+		{`\"a`, shelltoken.SplitNoOptions, []shelltoken.Token{{`"a`, 0, 3}}},
+		{`äö "ü ß" 日本`, shelltoken.SplitNoOptions, []shelltoken.Token{{"äö", 0, 4}, {"ü ß", 5, 12}, {"日本", 13, 19}}},
+		{`a  "b"`, shelltoken.SplitKeepSeparator, []shelltoken.Token{{"a", 0, 1}, {" ", 1, 2}, {" ", 2, 3}, {"b", 3, 6}}},
+		{"a　b", shelltoken.SplitKeepSeparator, []shelltoken.Token{{"a", 0, 1}, {"　", 1, 4}, {"b", 4, 5}}},
+		{`'a' b`, shelltoken.SplitKeepQuotes, []shelltoken.Token{{"'a'", 0, 3}, {"b", 4, 5}}},
+	}
+
+	for _, tst := range tests {
+		tokens, err := shelltoken.SplitQuotesWithPositions(tst.in, shelltoken.Whitespace+"　", tst.options)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, tokens, "Tokenize: %v -> %v", tst.in, tokens)
+	}
+
+	tokens, err := shelltoken.SplitQuotesWithPositions(`a "b`, shelltoken.Whitespace)
+	require.EqualError(t, err, `unbalanced " quote opened at position 2`)
+	assert.Nil(t, tokens)
+}
+
+func TestSplitQuotesRaw(t *testing.T) {
+	tests := []struct {
+		in      string
+		options shelltoken.SplitOption
+		res     []shelltoken.RawToken
+	}{
+		{"", shelltoken.SplitNoOptions, []shelltoken.RawToken{}},
+		{`ls  -l`, shelltoken.SplitNoOptions, []shelltoken.RawToken{{"ls", "ls"}, {"-l", "-l"}}},
+		{`'a b' "c d"e`, shelltoken.SplitNoOptions, []shelltoken.RawToken{{"a b", "'a b'"}, {"c de", `"c d"e`}}},
+		{`a\ b "x\"y" \$z`, shelltoken.SplitNoOptions, []shelltoken.RawToken{{"a b", `a\ b`}, {`x"y`, `"x\"y"`}, {"$z", `\$z`}}},
+		{`'' "ü ß"`, shelltoken.SplitNoOptions, []shelltoken.RawToken{{"", "''"}, {"ü ß", `"ü ß"`}}},
+		{`'a b'`, shelltoken.SplitKeepQuotes, []shelltoken.RawToken{{"'a b'", "'a b'"}}},
+	}
+
+	for _, tst := range tests {
+		tokens, err := shelltoken.SplitQuotesRaw(tst.in, shelltoken.Whitespace, tst.options)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, tokens, "Tokenize: %v -> %v", tst.in, tokens)
+	}
+
+	tokens, err := shelltoken.SplitQuotesRaw(`a "b`, shelltoken.Whitespace)
+	require.EqualError(t, err, `unbalanced " quote opened at position 2`)
+	assert.Nil(t, tokens)
+}
+
+func TestSplitDetailed(t *testing.T) {
+	tests := []struct {
+		in   string
+		env  []string
+		argv []string
+		seps []string
+	}{
+		{"", []string{}, []string{""}, []string{""}},
+		{"ls -l", []string{}, []string{"ls", "-l"}, []string{"", " "}},
+		{"  ls\t-l \n 'a b'  ", []string{}, []string{"ls", "-l", "a b"}, []string{"  ", "\t", " \n "}},
+		{" A=1  B=2\tls  -l", []string{"A=1", "B=2"}, []string{"ls", "-l"}, []string{"\t", "  "}},
+		{"A=1 ", []string{"A=1"}, []string{""}, []string{" "}},
+	}
+
+	for _, tst := range tests {
+		env, argv, seps, err := shelltoken.SplitDetailed(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.env, env, "env of: %q", tst.in)
+		assert.Equalf(t, tst.argv, argv, "argv of: %q", tst.in)
+		assert.Equalf(t, tst.seps, seps, "seps of: %q", tst.in)
+	}
+
+	_, _, _, err := shelltoken.SplitDetailed("ls | wc")
+	require.Error(t, err)
+}
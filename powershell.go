@@ -0,0 +1,177 @@
+package shelltoken
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PowerShellStream identifies a PowerShell output stream.
+type PowerShellStream uint8
+
+const (
+	// PowerShellStreamAll is the "*" stream selector matching all streams.
+	PowerShellStreamAll PowerShellStream = iota
+
+	// PowerShellStreamSuccess is stream 1, the default output.
+	PowerShellStreamSuccess
+
+	// PowerShellStreamError is stream 2.
+	PowerShellStreamError
+
+	// PowerShellStreamWarning is stream 3.
+	PowerShellStreamWarning
+
+	// PowerShellStreamVerbose is stream 4.
+	PowerShellStreamVerbose
+
+	// PowerShellStreamDebug is stream 5.
+	PowerShellStreamDebug
+
+	// PowerShellStreamInformation is stream 6.
+	PowerShellStreamInformation
+)
+
+func (s PowerShellStream) String() string {
+	switch s {
+	case PowerShellStreamAll:
+		return "All"
+	case PowerShellStreamSuccess:
+		return "Success"
+	case PowerShellStreamError:
+		return "Error"
+	case PowerShellStreamWarning:
+		return "Warning"
+	case PowerShellStreamVerbose:
+		return "Verbose"
+	case PowerShellStreamDebug:
+		return "Debug"
+	case PowerShellStreamInformation:
+		return "Information"
+	}
+
+	return fmt.Sprintf("PowerShellStream(%d)", s)
+}
+
+// PowerShellRedirection is a parsed PowerShell redirection like "2>err.txt", "*>>all.log" or "2>&1".
+type PowerShellRedirection struct {
+	Operator string           // redirection operator as written, ex.: "2>>"
+	Stream   PowerShellStream // redirected stream
+	Append   bool             // true for ">>"
+	Merge    bool             // true if the stream is merged into the success stream (">&1")
+	Target   string           // target file, empty if merged
+}
+
+// SplitPowerShell will tokenize a string the way PowerShell does it and
+// splits leading environment variables from the command like SplitWindows does.
+// It uses
+// - separator: " \t\n\r".
+// - escape character: "`", a backtick escapes the next character, also inside double quotes.
+// - single quotes: literal strings without escapes.
+// - doubled quotes: true, two quotes inside quotes produce a literal quote.
+// returns error if shell characters were found.
+func SplitPowerShell(str string) (env, argv []string, err error) {
+	cfg := NewConfig(Whitespace, powerShellOptions)
+	cfg.EscapeChar = '`'
+
+	argv, err = cfg.Split(strings.TrimSpace(str))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	env, argv = ExtractEnvFromArgv(argv)
+	if len(argv) == 0 {
+		// input consisting only of assignments has an empty command
+		argv = []string{""}
+	}
+
+	return env, argv, nil
+}
+
+// MissingRedirectionTargetError is returned if a redirection is not followed by a target.
+type MissingRedirectionTargetError struct {
+	Pos int // position of the redirection operator
+}
+
+func (e *MissingRedirectionTargetError) Error() string {
+	return fmt.Sprintf("missing redirection target at position %d", e.Pos)
+}
+
+// SplitPowerShellRedirections will tokenize a string like SplitWindows does but
+// extracts PowerShell redirections from the argument list.
+// Supported redirections are ">", ">>", "n>", "n>>", "*>", "*>>", "n>&1" and "*>&1"
+// with n being a stream number from 1 to 6. The target can either be attached to
+// the operator ("2>err.txt") or follow as next token ("2> err.txt").
+// Redirections must start a token, quoted operators are regular arguments.
+func SplitPowerShellRedirections(str string) (argv []string, redirections []PowerShellRedirection, err error) {
+	cfg := NewConfig(Whitespace, SplitKeepBackslashes|SplitIgnoreBackslashes)
+
+	tokens, err := splitTokens(str, &cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	argv = []string{}
+	redirections = []PowerShellRedirection{}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		redir, ok := parsePowerShellRedirection(str[token.Start:token.End])
+		if !ok {
+			argv = append(argv, token.Value)
+
+			continue
+		}
+
+		redir.Target = token.Value[len(redir.Operator):]
+		if redir.Target == "" && !redir.Merge {
+			// target is the next token
+			i++
+			if i >= len(tokens) {
+				return nil, nil, &MissingRedirectionTargetError{Pos: token.Start}
+			}
+
+			redir.Target = tokens[i].Value
+		}
+
+		redirections = append(redirections, redir)
+	}
+
+	return argv, redirections, nil
+}
+
+// parsePowerShellRedirection parses the redirection operator at the start of the raw token.
+func parsePowerShellRedirection(raw string) (redir PowerShellRedirection, ok bool) {
+	redir.Stream = PowerShellStreamSuccess
+	pos := 0
+
+	switch {
+	case raw == "":
+		return redir, false
+	case raw[0] == '*':
+		redir.Stream = PowerShellStreamAll
+		pos++
+	case raw[0] >= '1' && raw[0] <= '6':
+		redir.Stream = PowerShellStream(raw[0] - '0')
+		pos++
+	}
+
+	if pos >= len(raw) || raw[pos] != '>' {
+		return redir, false
+	}
+
+	pos++
+
+	switch {
+	case pos < len(raw) && raw[pos] == '>':
+		redir.Append = true
+		pos++
+	case raw[pos:] == "&1":
+		redir.Merge = true
+		pos += 2
+	}
+
+	redir.Operator = raw[:pos]
+
+	return redir, true
+}
@@ -0,0 +1,90 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitPowerShell(t *testing.T) {
+	tests := []struct {
+		in   string
+		env  []string
+		argv []string
+	}{
+		{"Write-Host `\"hi`\"", []string{}, []string{"Write-Host", `"hi"`}},
+		{`echo 'it''s fine'`, []string{}, []string{"echo", "it's fine"}},
+		{`echo "say ""hi""" 'a\b' "a\b"`, []string{}, []string{"echo", `say "hi"`, `a\b`, `a\b`}},
+		{"echo \"a`\"b`'c\" 'a`b'", []string{}, []string{"echo", "a\"b'c", "a`b"}},
+		{`C:\Program` + "` " + `Files\app.exe -x`, []string{}, []string{`C:\Program Files\app.exe`, "-x"}},
+		{"A=1 B=2 app.exe", []string{"A=1", "B=2"}, []string{"app.exe"}},
+		{"", []string{}, []string{""}},
+	}
+
+	for _, tst := range tests {
+		env, argv, err := shelltoken.SplitPowerShell(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.env, env, "env: %v -> %v", tst.in, env)
+		assert.Equalf(t, tst.argv, argv, "argv: %v -> %v", tst.in, argv)
+	}
+
+	_, _, err := shelltoken.SplitPowerShell("echo a | b")
+	require.Error(t, err)
+
+	_, _, err = shelltoken.SplitPowerShell("echo 'a")
+	require.Error(t, err)
+}
+
+func TestSplitPowerShellRedirections(t *testing.T) {
+	tests := []struct {
+		in    string
+		res   []string
+		redir []shelltoken.PowerShellRedirection
+	}{
+		{`Get-Item x`, []string{"Get-Item", "x"}, []shelltoken.PowerShellRedirection{}},
+		{`Get-Item x > out.txt`, []string{"Get-Item", "x"}, []shelltoken.PowerShellRedirection{
+			{Operator: ">", Stream: shelltoken.PowerShellStreamSuccess, Target: "out.txt"},
+		}},
+		{`Get-Item x 2>&1 >>out.txt`, []string{"Get-Item", "x"}, []shelltoken.PowerShellRedirection{
+			{Operator: "2>&1", Stream: shelltoken.PowerShellStreamError, Merge: true},
+			{Operator: ">>", Stream: shelltoken.PowerShellStreamSuccess, Append: true, Target: "out.txt"},
+		}},
+		{`Get-Item x *> 'C:\all files.log' 3>warn.txt`, []string{"Get-Item", "x"}, []shelltoken.PowerShellRedirection{
+			{Operator: "*>", Stream: shelltoken.PowerShellStreamAll, Target: `C:\all files.log`},
+			{Operator: "3>", Stream: shelltoken.PowerShellStreamWarning, Target: "warn.txt"},
+		}},
+		{`Write-Host "2>x" '>' 7>x`, []string{"Write-Host", "2>x", ">", "7>x"}, []shelltoken.PowerShellRedirection{}},
+		{`Get-Item x 6>>"info.txt" *>&1`, []string{"Get-Item", "x"}, []shelltoken.PowerShellRedirection{
+			{Operator: "6>>", Stream: shelltoken.PowerShellStreamInformation, Append: true, Target: "info.txt"},
+			{Operator: "*>&1", Stream: shelltoken.PowerShellStreamAll, Merge: true},
+		}},
+	}
+
+	for _, tst := range tests {
+		argv, redir, err := shelltoken.SplitPowerShellRedirections(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+		assert.Equalf(t, tst.redir, redir, "Redirections: %v -> %v", tst.in, redir)
+	}
+}
+
+func TestSplitPowerShellRedirectionsErrors(t *testing.T) {
+	argv, redir, err := shelltoken.SplitPowerShellRedirections(`Get-Item x 2>`)
+	targetErr := &shelltoken.MissingRedirectionTargetError{}
+	require.ErrorAs(t, err, &targetErr)
+	assert.Equal(t, 11, targetErr.Pos)
+	assert.Nil(t, argv)
+	assert.Nil(t, redir)
+
+	_, _, err = shelltoken.SplitPowerShellRedirections(`Get-Item "x`)
+	assert.EqualError(t, err, `unbalanced " quote opened at position 9`)
+}
+
+func TestPowerShellStreamString(t *testing.T) {
+	assert.Equal(t, "All", shelltoken.PowerShellStreamAll.String())
+	assert.Equal(t, "Error", shelltoken.PowerShellStreamError.String())
+	assert.Equal(t, "Information", shelltoken.PowerShellStreamInformation.String())
+	assert.Equal(t, "PowerShellStream(9)", shelltoken.PowerShellStream(9).String())
+}
@@ -0,0 +1,224 @@
+package shelltoken
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Join is the reverse of SplitLinux and joins argv into a single command line.
+// Elements which contain whitespace, quotes, backslashes or shell characters
+// or start with a "#" are put into single quotes, all other elements are added
+// unchanged. The first element is quoted as well if it looks like an environment
+// assignment, so a shell runs it as command.
+// Single quotes are escaped by closing the quoted string, adding an escaped
+// quote and reopening it, so the result is safe to use in a POSIX shell.
+// SplitLinux(Join(argv)) returns argv again unless argv[0] is an assignment,
+// which SplitLinux extracts as env regardless of quotes.
+func Join(argv []string) string {
+	cmdLine := strings.Builder{}
+
+	for i, arg := range argv {
+		switch {
+		case i > 0:
+			cmdLine.WriteByte(' ')
+			writeQuoted(&cmdLine, arg)
+		case isEnvAssignment(arg):
+			writeSingleQuoted(&cmdLine, arg)
+		default:
+			writeQuoted(&cmdLine, arg)
+		}
+	}
+
+	return cmdLine.String()
+}
+
+// BuildCommandLine is the reverse of SplitLinux and joins env and argv into a
+// single command line. Each env element is written as KEY=VALUE with the value
+// quoted like in Join, followed by argv joined by Join.
+// SplitLinux(BuildCommandLine(env, argv)) returns env and argv again unless
+// argv[0] looks like an environment assignment itself.
+func BuildCommandLine(env, argv []string) string {
+	cmdLine := strings.Builder{}
+
+	for i, assignment := range env {
+		if i > 0 {
+			cmdLine.WriteByte(' ')
+		}
+
+		key, value, ok := strings.Cut(assignment, "=")
+		if !ok || !isValidEnvKey(strings.TrimSuffix(key, "+")) {
+			writeQuoted(&cmdLine, assignment)
+		} else {
+			cmdLine.WriteString(key)
+			cmdLine.WriteByte('=')
+
+			if value != "" {
+				writeQuoted(&cmdLine, value)
+			}
+		}
+	}
+
+	if len(env) > 0 && len(argv) > 0 {
+		cmdLine.WriteByte(' ')
+	}
+
+	cmdLine.WriteString(Join(argv))
+
+	return cmdLine.String()
+}
+
+// Normalize canonicalizes the quoting of a command line, so command lines which
+// are semantically identical also have the same text, ex.: "a"'b'c results in abc.
+// The command line is tokenized like SplitLinux and written by BuildCommandLine,
+// so tokens are only quoted if required. An "export" before environment
+// assignments is removed. Normalizing the result again returns it unchanged.
+func Normalize(str string) (string, error) {
+	tokens, err := SplitQuotes(strings.TrimSpace(str), Whitespace, linuxOptions)
+	if err != nil {
+		return "", err
+	}
+
+	envIndex, cmd := extractEnvIndex(tokens)
+
+	env := make([]string, 0, len(envIndex))
+	for _, i := range envIndex {
+		env = append(env, tokens[i])
+	}
+
+	return BuildCommandLine(env, tokens[cmd:]), nil
+}
+
+// writeQuoted writes arg to cmdLine and puts it into single quotes if required.
+func writeQuoted(cmdLine *strings.Builder, arg string) {
+	if !NeedsQuoting(arg) {
+		cmdLine.WriteString(arg)
+
+		return
+	}
+
+	writeSingleQuoted(cmdLine, arg)
+}
+
+// writeSingleQuoted writes arg to cmdLine in single quotes.
+func writeSingleQuoted(cmdLine *strings.Builder, arg string) {
+	cmdLine.WriteByte('\'')
+	cmdLine.WriteString(strings.ReplaceAll(arg, `'`, `'\''`))
+	cmdLine.WriteByte('\'')
+}
+
+// JoinWindows joins argv into a single windows command line following the
+// CommandLineToArgvW quoting rules.
+// Elements which contain whitespace, quotes or shell characters are put into
+// double quotes. Embedded double quotes are doubled and backslashes are
+// doubled only if they precede a double quote.
+// SplitWindows(JoinWindows(argv)) returns argv again unless an element has
+// backslashes in front of a double quote or at the end of a quoted element,
+// since SplitWindows keeps all backslashes literally.
+func JoinWindows(argv []string) string {
+	cmdLine := strings.Builder{}
+
+	for i, arg := range argv {
+		if i > 0 {
+			cmdLine.WriteByte(' ')
+		}
+
+		if !needsWindowsQuoting(arg) {
+			cmdLine.WriteString(arg)
+
+			continue
+		}
+
+		cmdLine.WriteByte('"')
+
+		slashes := 0
+
+		for j := 0; j < len(arg); j++ {
+			char := arg[j]
+			switch char {
+			case '\\':
+				slashes++
+			case '"':
+				writeBackslashes(&cmdLine, slashes)
+				cmdLine.WriteByte('"')
+
+				slashes = 0
+			default:
+				slashes = 0
+			}
+
+			cmdLine.WriteByte(char)
+		}
+
+		writeBackslashes(&cmdLine, slashes)
+		cmdLine.WriteByte('"')
+	}
+
+	return cmdLine.String()
+}
+
+// TrimMatchingQuotes removes a single pair of surrounding quotes if s starts
+// and ends with the same quote character. The content between the quotes is
+// returned verbatim, escapes are not processed.
+// Otherwise s is returned unchanged.
+func TrimMatchingQuotes(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+
+	switch s[0] {
+	case '"', '\'':
+		if s[len(s)-1] == s[0] {
+			return s[1 : len(s)-1]
+		}
+	}
+
+	return s
+}
+
+// NeedsQuoting returns true if str cannot be used as bare word, i.e. if it is
+// empty, starts a comment with "#" or contains whitespace, quotes, a backslash
+// or any of OutsideQuoteShellCharacters.
+// Strings which do not need quoting are passed through unchanged by SplitLinux.
+func NeedsQuoting(str string) bool {
+	if str == "" || str[0] == '#' {
+		return true
+	}
+
+	for _, char := range str {
+		if char == '\\' || isSpecialRune(char) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// needsWindowsQuoting returns true if str cannot be used as bare word on windows,
+// where backslashes have no special meaning.
+func needsWindowsQuoting(str string) bool {
+	if str == "" {
+		return true
+	}
+
+	for _, char := range str {
+		if isSpecialRune(char) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSpecialRune returns true for whitespace, quotes and shell characters.
+func isSpecialRune(char rune) bool {
+	switch {
+	case char == '"', char == '\'':
+		return true
+	case unicode.IsSpace(char):
+		return true
+	case strings.ContainsRune(Whitespace, char), strings.ContainsRune(OutsideQuoteShellCharacters, char):
+		return true
+	}
+
+	return false
+}
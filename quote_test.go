@@ -0,0 +1,251 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrimMatchingQuotes(t *testing.T) {
+	tests := []struct {
+		in  string
+		res string
+	}{
+		{``, ``},
+		{`'`, `'`},
+		{`"`, `"`},
+		{`''`, ``},
+		{`""`, ``},
+		{`'a'`, `a`},
+		{`"a b"`, `a b`},
+		{`'a"`, `'a"`},
+		{`"a'`, `"a'`},
+		{`a`, `a`},
+		{`'a`, `'a`},
+		{`a'`, `a'`},
+		{`'a'b'c'`, `a'b'c`},
+		{`"\"a\""`, `\"a\"`},
+		{`''a''`, `'a'`},
+	}
+
+	for _, tst := range tests {
+		res := shelltoken.TrimMatchingQuotes(tst.in)
+		assert.Equalf(t, tst.res, res, "TrimMatchingQuotes: %s -> %s", tst.in, res)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		in  []string
+		res string
+	}{
+		{[]string{}, ``},
+		{[]string{""}, `''`},
+		{[]string{"ls", "-l", "/tmp"}, `ls -l /tmp`},
+		{[]string{"echo", "a b"}, `echo 'a b'`},
+		{[]string{"echo", "it's"}, `echo 'it'\''s'`},
+		{[]string{"echo", `"x"`}, `echo '"x"'`},
+		{[]string{"echo", `a\b`}, `echo 'a\b'`},
+		{[]string{"echo", "`id`", "$HOME", "a|b"}, "echo '`id`' '$HOME' 'a|b'"},
+		{[]string{"echo", "a\nb"}, "echo 'a\nb'"},
+		{[]string{"echo", "#foo", "a#b"}, "echo '#foo' a#b"},
+		{[]string{"A=1", "B=2"}, "'A=1' B=2"},
+	}
+
+	for _, tst := range tests {
+		res := shelltoken.Join(tst.in)
+		assert.Equalf(t, tst.res, res, "Join: %#v -> %s", tst.in, res)
+	}
+}
+
+func TestJoinRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{""},
+		{"cmd", ""},
+		{"cmd", "", "", "x"},
+		{"echo", "a b", "c\td", "e\nf", "\r"},
+		{"echo", "it's", "'", "''", `'\''`},
+		{"echo", `"`, `\`, `\\`, `\"`, `a\ b`},
+		{"echo", "`whoami`", "$(id)", "${HOME}", "a;b", "a&&b", "x > y", "~", "*.txt", "!"},
+		{"echo", " ", "ü ö", "日本語"},
+		{"/bin/sh", "-c", "echo 'a b' | wc -l"},
+		{"echo", "#foo", "#", "a#b", "a=b", "A=1"},
+	}
+
+	for _, argv := range tests {
+		cmdLine := shelltoken.Join(argv)
+		env, res, err := shelltoken.SplitLinux(cmdLine)
+		require.NoErrorf(t, err, "error while parsing: %s", cmdLine)
+		assert.Emptyf(t, env, "no env: %s", cmdLine)
+		assert.Equalf(t, argv, res, "round trip: %#v -> %s -> %#v", argv, cmdLine, res)
+	}
+
+	// a shell runs a quoted assignment as command, SplitLinux extracts it as env anyway
+	argv := []string{"A=1", "#x", "B=2"}
+	cmdLine := shelltoken.Join(argv)
+	assert.Equal(t, "'A=1' '#x' B=2", cmdLine)
+
+	_, res, err := shelltoken.SplitLinuxNoEnv(cmdLine)
+	require.NoError(t, err)
+	assert.Equal(t, argv, res)
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in  string
+		res string
+	}{
+		{``, ``},
+		{`  ls   -l  `, `ls -l`},
+		{`"a"'b'c`, `abc`},
+		{`"ls" '-l' "/tmp"`, `ls -l /tmp`},
+		{`echo "a b" a\ b 'a'" "b`, `echo 'a b' 'a b' 'a b'`},
+		{`echo "it's" it\'s`, `echo 'it'\''s' 'it'\''s'`},
+		{`echo "" ''`, `echo '' ''`},
+		{`A="1" export B='x y' "cmd" "A=2"`, `A=1 B='x y' cmd A=2`},
+		{`A="a b"`, `A='a b'`},
+		{`echo '$HOME' '\$x'`, `echo '$HOME' '\$x'`},
+	}
+
+	for _, tst := range tests {
+		res, err := shelltoken.Normalize(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, res, "Normalize: %s -> %s", tst.in, res)
+
+		again, err := shelltoken.Normalize(res)
+		require.NoErrorf(t, err, "error while parsing: %s", res)
+		assert.Equalf(t, res, again, "Normalize is stable: %s -> %s", res, again)
+
+		env, argv, err := shelltoken.SplitLinux(tst.in)
+		require.NoError(t, err)
+
+		normEnv, normArgv, err := shelltoken.SplitLinux(res)
+		require.NoError(t, err)
+		assert.Equalf(t, env, normEnv, "env of: %s", tst.in)
+		assert.Equalf(t, argv, normArgv, "argv of: %s", tst.in)
+	}
+
+	_, err := shelltoken.Normalize(`echo "a`)
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+
+	_, err = shelltoken.Normalize(`echo a | wc`)
+	require.Error(t, err)
+}
+
+func TestBuildCommandLine(t *testing.T) {
+	tests := []struct {
+		env  []string
+		argv []string
+		res  string
+	}{
+		{nil, nil, ``},
+		{[]string{}, []string{"ls", "-l"}, `ls -l`},
+		{[]string{"A=1"}, []string{}, `A=1`},
+		{[]string{"A=1", "B="}, []string{"ls"}, `A=1 B= ls`},
+		{[]string{"A=a b", "B=it's"}, []string{"echo", "$A"}, `A='a b' B='it'\''s' echo '$A'`},
+		{[]string{"A==|"}, []string{"cmd"}, `A='=|' cmd`},
+	}
+
+	for _, tst := range tests {
+		res := shelltoken.BuildCommandLine(tst.env, tst.argv)
+		assert.Equalf(t, tst.res, res, "BuildCommandLine: %#v %#v -> %s", tst.env, tst.argv, res)
+	}
+}
+
+func TestBuildCommandLineRoundTrip(t *testing.T) {
+	tests := []struct {
+		env  []string
+		argv []string
+	}{
+		{[]string{}, []string{"cmd"}},
+		{[]string{"A=1"}, []string{"cmd", "x=y"}},
+		{[]string{"A=", "B=a b", "C=it's", "D=$(id)", "E=a\nb", "_F=x=y"}, []string{"echo", "a b", "'"}},
+		{[]string{"PATH=/bin:/usr/bin"}, []string{"/bin/sh", "-c", "echo 'a b' | wc -l"}},
+	}
+
+	for _, tst := range tests {
+		cmdLine := shelltoken.BuildCommandLine(tst.env, tst.argv)
+		env, argv, err := shelltoken.SplitLinux(cmdLine)
+		require.NoErrorf(t, err, "error while parsing: %s", cmdLine)
+		assert.Equalf(t, tst.env, env, "env round trip: %#v -> %s -> %#v", tst.env, cmdLine, env)
+		assert.Equalf(t, tst.argv, argv, "argv round trip: %#v -> %s -> %#v", tst.argv, cmdLine, argv)
+	}
+}
+
+func TestJoinWindows(t *testing.T) {
+	tests := []struct {
+		in  []string
+		res string
+	}{
+		{[]string{}, ``},
+		{[]string{""}, `""`},
+		{[]string{`C:\Windows\notepad.exe`, `x.txt`}, `C:\Windows\notepad.exe x.txt`},
+		{[]string{`C:\Program Files\app.exe`, `-x`}, `"C:\Program Files\app.exe" -x`},
+		{[]string{`say "hi"`}, `"say ""hi"""`},
+		{[]string{`it's`}, `"it's"`},
+		{[]string{`C:\dir x\`}, `"C:\dir x\\"`},
+		{[]string{`a\"b`}, `"a\\""b"`},
+		{[]string{`a|b`}, `"a|b"`},
+	}
+
+	for _, tst := range tests {
+		res := shelltoken.JoinWindows(tst.in)
+		assert.Equalf(t, tst.res, res, "JoinWindows: %#v -> %s", tst.in, res)
+	}
+}
+
+func TestJoinWindowsRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{`C:\Program Files\app.exe`},
+		{`C:\Program Files\app.exe`, `--file=C:\some dir\x.txt`, `-v`},
+		{`cmd`, `say "hi"`, `"`, `""`, `it's`, `'`, ``},
+		{`\\server\share\a b`, `a\b`, "a\tb"},
+	}
+
+	for _, argv := range tests {
+		cmdLine := shelltoken.JoinWindows(argv)
+		env, res, err := shelltoken.SplitWindows(cmdLine)
+		require.NoErrorf(t, err, "error while parsing: %s", cmdLine)
+		assert.Emptyf(t, env, "no env: %s", cmdLine)
+		assert.Equalf(t, argv, res, "round trip: %#v -> %s -> %#v", argv, cmdLine, res)
+	}
+}
+
+func TestNeedsQuoting(t *testing.T) {
+	tests := []struct {
+		in  string
+		res bool
+	}{
+		{"", true},
+		{"word", false},
+		{"/usr/bin/test-1.2_x", false},
+		{"key=value", false},
+		{"a b", true},
+		{"a\tb", true},
+		{"a\nb", true},
+		{`a"b`, true},
+		{"a'b", true},
+		{`a\b`, true},
+		{"a$b", true},
+		{"a`b", true},
+		{"a|b", true},
+		{"a;b", true},
+		{"a&b", true},
+		{"a*b", true},
+		{"a(b)", true},
+		{"~a", true},
+		{"a>b", true},
+	}
+
+	for _, tst := range tests {
+		assert.Equalf(t, tst.res, shelltoken.NeedsQuoting(tst.in), "NeedsQuoting: %q", tst.in)
+
+		if !tst.res {
+			_, argv, err := shelltoken.SplitLinux("cmd " + tst.in)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"cmd", tst.in}, argv)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package shelltoken
+
+import "strings"
+
+// redirectionOperators are recognized by SplitRedirections, longest first.
+var redirectionOperators = []string{"&>>", "&>", ">>", ">&", "<&", "<>", ">|", ">", "<"}
+
+// matchRedirection returns the redirection at pos and its start position. A number
+// right before it is included as file descriptor, as is the duplicated descriptor of ">&" and "<&".
+// Returns an empty string if there is no redirection at pos.
+func (p *parseState) matchRedirection(char rune, pos int) (redirection string, start int) {
+	if !p.redirections || p.inSingleQuotes || p.inDoubleQuotes || !strings.ContainsRune("<>&", char) {
+		return "", 0
+	}
+
+	start = pos
+	if p.hasToken && char != '&' {
+		// a plain number right before the redirection is the file descriptor
+		prefix := p.str[p.tokenStart:pos]
+		if prefix == string(p.token.Bytes()) && strings.Trim(prefix, "0123456789") == "" {
+			start = p.tokenStart
+		}
+	}
+
+	operator := ""
+
+	for _, op := range redirectionOperators {
+		if strings.HasPrefix(p.str[pos:], op) {
+			operator = op
+
+			break
+		}
+	}
+
+	end := pos + len(operator)
+
+	switch operator {
+	case "":
+		return "", 0
+	case "<":
+		if strings.HasPrefix(p.str[end:], "<") || strings.HasPrefix(p.str[end:], "(") {
+			// here-documents, here-strings and process substitutions are no redirections
+			return "", 0
+		}
+	case ">":
+		if strings.HasPrefix(p.str[end:], "(") {
+			return "", 0
+		}
+	case ">&", "<&":
+		switch {
+		case strings.HasPrefix(p.str[end:], "-"):
+			end++
+		default:
+			for end < len(p.str) && p.str[end] >= '0' && p.str[end] <= '9' {
+				end++
+			}
+		}
+	}
+
+	return p.str[start:end], start
+}
+
+// addRedirection emits the redirection which starts at start and replaces the current token.
+func (p *parseState) addRedirection(redirection string, start int) {
+	if start == p.tokenStart {
+		// the current token is the file descriptor of the redirection
+		p.token.Reset()
+		p.hasToken = false
+		p.tokenStart = -1
+	} else {
+		p.flushToken(start)
+	}
+
+	p.emitToken(redirection, start, start+len(redirection))
+	p.skip = start + len(redirection)
+}
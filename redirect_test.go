@@ -0,0 +1,41 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitRedirections(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{"cmd 2>&1", []string{"cmd", "2>&1"}},
+		{"cmd >out 2>err", []string{"cmd", ">", "out", "2>", "err"}},
+		{"cmd &>all", []string{"cmd", "&>", "all"}},
+		{"cmd &>>all <in", []string{"cmd", "&>>", "all", "<", "in"}},
+		{"cmd 10>>log 3<>rw 0<&3 >&- 2>|x", []string{"cmd", "10>>", "log", "3<>", "rw", "0<&3", ">&-", "2>|", "x"}},
+		{"cmd>out", []string{"cmd", ">", "out"}},
+		{"cmd a2>x", []string{"cmd", "a2", ">", "x"}},
+		{`cmd "2">x '>'`, []string{"cmd", "2", ">", "x", ">"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitRedirections|shelltoken.SplitStopOnShellCharacters)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	// other shell characters are still detected
+	for _, in := range []string{"cmd & x", "cmd 2>&1 | wc", "cat <(ls)", "cat <<EOF", "cat <<<word", "cmd >(x)"} {
+		_, err := shelltoken.SplitQuotes(in, shelltoken.Whitespace, shelltoken.SplitRedirections|shelltoken.SplitStopOnShellCharacters)
+		require.ErrorIsf(t, err, shelltoken.ErrShellCharacters, "expected shell character error for: %s", in)
+	}
+
+	// without the option redirections are shell characters
+	_, err := shelltoken.SplitQuotes("cmd 2>&1", shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters)
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+}
@@ -0,0 +1,107 @@
+package shelltoken
+
+import (
+	"strings"
+)
+
+// SplitResponseFile will tokenize the content of a response file (@file.rsp) as
+// read by windows build tools like nmake, msbuild or the msvc compiler:
+// - arguments are separated by any whitespace including newlines.
+// - lines starting with a # are comments and will be ignored.
+// - double quotes group arguments, newlines within quotes are kept.
+// - backslashes follow the rules described in SplitGoExecWindows.
+// An UnbalancedQuotesError is returned if a double quote is not closed.
+func SplitResponseFile(str string) (argv []string, err error) {
+	argv = []string{}
+	token := strings.Builder{}
+	inToken := false
+	inQuotes := false
+	quoteStart := 0
+	lineStart := true
+	slashes := 0
+
+	for pos := 0; pos < len(str); pos++ {
+		char := str[pos]
+
+		if !inQuotes && isResponseFileSpace(char) {
+			if inToken {
+				writeBackslashes(&token, slashes)
+				argv = append(argv, token.String())
+				token.Reset()
+				inToken = false
+				slashes = 0
+			}
+
+			if char == '\n' {
+				lineStart = true
+			}
+
+			continue
+		}
+
+		if lineStart && char == '#' {
+			// skip comment until the end of the line
+			for pos < len(str) && str[pos] != '\n' {
+				pos++
+			}
+
+			continue
+		}
+
+		lineStart = false
+		inToken = true
+
+		switch char {
+		case '"':
+			writeBackslashes(&token, slashes/2)
+
+			switch {
+			case slashes%2 == 1:
+				token.WriteByte(char)
+			case inQuotes && pos+1 < len(str) && str[pos+1] == '"':
+				// two double quotes inside quotes produce a literal quote
+				token.WriteByte(char)
+
+				pos++
+				inQuotes = false
+			default:
+				inQuotes = !inQuotes
+				quoteStart = pos
+			}
+
+			slashes = 0
+
+			continue
+		case '\\':
+			slashes++
+
+			continue
+		}
+
+		writeBackslashes(&token, slashes)
+		slashes = 0
+
+		token.WriteByte(char)
+	}
+
+	if inQuotes {
+		return nil, &UnbalancedQuotesError{Quote: '"', Pos: quoteStart}
+	}
+
+	if inToken {
+		writeBackslashes(&token, slashes)
+		argv = append(argv, token.String())
+	}
+
+	return argv, nil
+}
+
+// isResponseFileSpace returns true if char separates arguments in response files.
+func isResponseFileSpace(char byte) bool {
+	switch char {
+	case ' ', '\t', '\r', '\n', '\v', '\f':
+		return true
+	}
+
+	return false
+}
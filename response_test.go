@@ -0,0 +1,40 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitResponseFile(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{``, []string{}},
+		{"\n\n", []string{}},
+		{`/nologo /W4`, []string{"/nologo", "/W4"}},
+		{"/c\r\n/Fo\"C:\\build dir\\out.obj\"\r\n\"C:\\Program Files\\src\\a.c\"\r\n", []string{"/c", `/FoC:\build dir\out.obj`, `C:\Program Files\src\a.c`}},
+		{"# compiler flags\n/O2\n  # indented comment\n/DNAME=\"a b\" # not a comment\n", []string{"/O2", "/DNAME=a b", "#", "not", "a", "comment"}},
+		{"a#b\n#c d\ne", []string{"a#b", "e"}},
+		{"\"multi\nline\" x", []string{"multi\nline", "x"}},
+		{`"" a`, []string{"", "a"}},
+		{`/I"C:\dir\\" x`, []string{`/IC:\dir\`, "x"}},
+		{`a\\\"b c\d`, []string{`a\"b`, `c\d`}},
+		{`"a"" b c\`, []string{`a"`, "b", `c\`}},
+		{"a\tb\vc\fd", []string{"a", "b", "c", "d"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitResponseFile(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "SplitResponseFile: %s -> %#v", tst.in, argv)
+	}
+
+	argv, err := shelltoken.SplitResponseFile("/c\n\"C:\\src\\a.c\n")
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+	require.EqualError(t, err, `unbalanced " quote opened at position 3`)
+	assert.Nil(t, argv)
+}
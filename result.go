@@ -0,0 +1,72 @@
+package shelltoken
+
+import "errors"
+
+// Result contains the tokens of a parse along with some metadata about it.
+type Result struct {
+	// Argv contains the command and its arguments.
+	Argv []string
+
+	// Env contains the leading environment assignments, see ExtractEnvFromArgv.
+	Env []string
+
+	// ShellCharPos is the byte offset of the first shell character or -1 if none was found.
+	// Shell characters are searched unless SplitIgnoreShellCharacters is set.
+	ShellCharPos int
+
+	// Splits is the number of tokens the input was split into, which is the
+	// number of Env and Argv entries together. It is zero on errors.
+	Splits int
+
+	// Truncated is set if parsing stopped because MaxTokens or MaxInputLen was exceeded.
+	Truncated bool
+}
+
+// SplitResult works like SplitQuotes but returns the tokens along with metadata
+// about the parse. Env and Argv are separated like ExtractEnvFromArgv does,
+// except that input consisting only of assignments results in an empty Argv.
+// On errors Argv and Env are nil, except for a TooManyTokensError which is
+// returned along with the tokens parsed so far.
+func SplitResult(str, sep string, options ...SplitOption) (Result, error) {
+	cfg := NewConfig(sep, options...)
+
+	return cfg.SplitResult(str)
+}
+
+// SplitResult works like Split but returns the tokens along with metadata
+// about the parse, see SplitResult.
+func (c Config) SplitResult(str string) (res Result, err error) {
+	res.ShellCharPos = -1
+	argv := make([]string, 0, max(c.CapacityHint, 0))
+	pst := newParseState(&c)
+	pst.ignShell = c.Options&SplitIgnoreShellCharacters > 0
+
+	err = pst.parse(str, func(token string, _, _ int) bool {
+		argv = append(argv, token)
+
+		return true
+	})
+
+	res.ShellCharPos = pst.firstShellPos
+
+	var tooLong *InputTooLongError
+	var tooMany *TooManyTokensError
+	res.Truncated = errors.As(err, &tooLong) || errors.As(err, &tooMany)
+
+	if err != nil && !pst.continueOnError(err) {
+		return res, err
+	}
+
+	res.Splits = len(argv)
+
+	envIndex, cmd := extractEnvIndex(argv)
+	res.Env = make([]string, 0, len(envIndex))
+
+	for _, i := range envIndex {
+		res.Env = append(res.Env, argv[i])
+	}
+
+	res.Argv = argv[cmd:]
+
+	return res, err
+}
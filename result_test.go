@@ -0,0 +1,50 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitResult(t *testing.T) {
+	tests := []struct {
+		in  string
+		res shelltoken.Result
+	}{
+		{"", shelltoken.Result{Argv: []string{}, Env: []string{}, ShellCharPos: -1}},
+		{"ls -l", shelltoken.Result{Argv: []string{"ls", "-l"}, Env: []string{}, ShellCharPos: -1, Splits: 2}},
+		{"A=1 B=2", shelltoken.Result{Argv: []string{}, Env: []string{"A=1", "B=2"}, ShellCharPos: -1, Splits: 2}},
+		{"A=1 B='2 3' ls", shelltoken.Result{Argv: []string{"ls"}, Env: []string{"A=1", "B=2 3"}, ShellCharPos: -1, Splits: 3}},
+		{"ls $HOME 'a|b' | wc", shelltoken.Result{Argv: []string{"ls", "$HOME", "a|b", "|", "wc"}, Env: []string{}, ShellCharPos: 3, Splits: 5}},
+	}
+
+	for _, tst := range tests {
+		res, err := shelltoken.SplitResult(tst.in, shelltoken.Whitespace)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, res, "Result: %v -> %v", tst.in, res)
+	}
+
+	res, err := shelltoken.SplitResult("ls 'a|b' | wc", shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters)
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+	assert.Equal(t, shelltoken.Result{ShellCharPos: 9}, res)
+}
+
+func TestConfigSplitResultTruncated(t *testing.T) {
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace)
+	cfg.MaxTokens = 2
+
+	res, err := cfg.SplitResult("A=1 ls -l")
+	require.ErrorAs(t, err, new(*shelltoken.TooManyTokensError))
+	assert.Equal(t, shelltoken.Result{Argv: []string{"ls"}, Env: []string{"A=1"}, ShellCharPos: -1, Splits: 2, Truncated: true}, res)
+
+	res, err = cfg.WithMaxInputLen(3).SplitResult("ls -l")
+	require.ErrorAs(t, err, new(*shelltoken.InputTooLongError))
+	assert.Equal(t, shelltoken.Result{ShellCharPos: -1, Truncated: true}, res)
+
+	res, err = cfg.SplitResult("ls")
+	require.NoError(t, err)
+	assert.False(t, res.Truncated)
+	assert.Equal(t, 1, res.Splits)
+}
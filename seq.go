@@ -0,0 +1,130 @@
+package shelltoken
+
+import (
+	"iter"
+	"strings"
+)
+
+// SplitSeq returns an iterator over the tokens of str.
+// It uses the same rules as SplitQuotes, but tokens are yielded as soon as they
+// are complete, so the caller can stop early without tokenizing the rest of str.
+// Errors are yielded as final pair with an empty token. Unlike SplitQuotes,
+// tokens found before the error have already been yielded at that point.
+func SplitSeq(str, sep string, options ...SplitOption) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		cfg := NewConfig(sep, options...)
+		pst := newParseState(&cfg)
+
+		err := pst.parse(str, func(token string, _, _ int) bool {
+			return yield(token, nil)
+		})
+		if err != nil {
+			yield("", err)
+		}
+	}
+}
+
+// SplitFunc calls fn for each token of str without building a result slice.
+// It uses the same rules and returns the same errors as SplitQuotes.
+// Tokens are passed to fn as soon as they are complete, so fn may already have
+// seen tokens of an input which later fails, ex.: with unbalanced quotes.
+// Returning false from fn stops parsing and SplitFunc returns nil. The rest of
+// str is not parsed then, so errors after that point, like an unclosed quote,
+// are not reported. Callers which need to validate the whole input must not stop early.
+func SplitFunc(str, sep string, fn func(token string) bool, options ...SplitOption) error {
+	cfg := NewConfig(sep, options...)
+	pst := newParseState(&cfg)
+
+	return pst.parse(str, func(token string, _, _ int) bool {
+		return fn(token)
+	})
+}
+
+// FirstToken returns the first token of str and the byte offset just past it.
+// It uses the same rules as SplitQuotes, but parsing stops as soon as the first
+// token is complete, so errors in the rest of str are not reported.
+// If str contains no token, an empty string and the length of str are returned.
+func FirstToken(str, sep string, options ...SplitOption) (token string, end int, err error) {
+	cfg := NewConfig(sep, options...)
+	pst := newParseState(&cfg)
+	end = len(str)
+
+	err = pst.parse(str, func(tok string, _, tokEnd int) bool {
+		token = tok
+		end = tokEnd
+
+		return false
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	return token, end, nil
+}
+
+// SplitQuotesN works like SplitQuotes but returns at most n elements, like strings.SplitN.
+// The first n-1 tokens are parsed and the last element holds the unparsed remainder
+// of str with leading separators removed. The remainder is omitted if it is empty.
+// A negative n returns all tokens, zero returns an empty list.
+// Errors in the remainder, like an unclosed quote, are not reported.
+func SplitQuotesN(str, sep string, n int, options ...SplitOption) ([]string, error) {
+	switch {
+	case n < 0:
+		return SplitQuotes(str, sep, options...)
+	case n == 0:
+		return []string{}, nil
+	}
+
+	cfg := NewConfig(sep, options...)
+	pst := newParseState(&cfg)
+
+	argv := []string{}
+	rest := 0
+
+	if n > 1 {
+		err := pst.parse(str, func(token string, _, end int) bool {
+			argv = append(argv, token)
+			rest = end
+
+			return len(argv) < n-1
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(argv) < n-1 {
+			// all tokens have been parsed
+			return argv, nil
+		}
+	}
+
+	if remainder := strings.TrimLeftFunc(str[rest:], pst.isSeparator); remainder != "" {
+		argv = append(argv, remainder)
+	}
+
+	return argv, nil
+}
+
+// SplitLeading returns the first token of str and the remainder after the separators
+// following it, ex.: `set "a b" c` results in "set" and `"a b" c`. The first token is
+// parsed like SplitQuotes, the remainder is returned verbatim without any processing.
+// If the first token cannot be parsed, ex.: because of an unclosed quote, head is empty
+// and rest holds str without leading separators.
+func SplitLeading(str, sep string) (head, rest string) {
+	cfg := NewConfig(sep)
+	pst := newParseState(&cfg)
+	end := 0
+
+	err := pst.parse(str, func(token string, _, tokEnd int) bool {
+		head = token
+		end = tokEnd
+
+		return false
+	})
+	if err != nil {
+		head = ""
+		end = 0
+	}
+
+	return head, strings.TrimLeftFunc(str[end:], pst.isSeparator)
+}
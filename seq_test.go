@@ -0,0 +1,179 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitSeq(t *testing.T) {
+	tests := []string{
+		"",
+		"a b c",
+		`echo "a b" 'c d' e\ f`,
+		"  multi\nline\t'input é'  ",
+	}
+
+	for _, str := range tests {
+		expect, err := shelltoken.SplitQuotes(str, shelltoken.Whitespace)
+		require.NoError(t, err)
+
+		tokens := []string{}
+		for token, err := range shelltoken.SplitSeq(str, shelltoken.Whitespace) {
+			require.NoErrorf(t, err, "error while parsing: %s", str)
+			tokens = append(tokens, token)
+		}
+
+		assert.Equalf(t, expect, tokens, "Tokenize: %v -> %v", str, tokens)
+	}
+}
+
+func TestFirstToken(t *testing.T) {
+	tests := []struct {
+		in    string
+		token string
+		end   int
+	}{
+		{"", "", 0},
+		{"   ", "", 3},
+		{"ls -l", "ls", 2},
+		{`  "a b" c`, "a b", 7},
+		{`a\ b'c d'e f "g`, "a bc de", 10},
+		{"ä ö", "ä", 2},
+	}
+
+	for _, tst := range tests {
+		token, end, err := shelltoken.FirstToken(tst.in, shelltoken.Whitespace)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.token, token, "first token of: %s", tst.in)
+		assert.Equalf(t, tst.end, end, "end of first token of: %s", tst.in)
+	}
+
+	_, _, err := shelltoken.FirstToken(`"a b`, shelltoken.Whitespace)
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+
+	_, _, err = shelltoken.FirstToken("$(id) a", shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters)
+	require.Error(t, err)
+}
+
+func TestSplitQuotesN(t *testing.T) {
+	tests := []struct {
+		in  string
+		n   int
+		res []string
+	}{
+		{"cmd a b c", 2, []string{"cmd", "a b c"}},
+		{"cmd a b c", -1, []string{"cmd", "a", "b", "c"}},
+		{"cmd a b c", 0, []string{}},
+		{"cmd a b c", 1, []string{"cmd a b c"}},
+		{"  cmd a  ", 1, []string{"cmd a  "}},
+		{"cmd a b c", 4, []string{"cmd", "a", "b", "c"}},
+		{"cmd a b c", 10, []string{"cmd", "a", "b", "c"}},
+		{`"my cmd"   'a b'  "c d`, 2, []string{"my cmd", `'a b'  "c d`}},
+		{`cmd  a\ b  `, 3, []string{"cmd", "a b"}},
+		{"", 2, []string{}},
+		{"   ", 1, []string{}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotesN(tst.in, shelltoken.Whitespace, tst.n)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "SplitQuotesN: %s (%d) -> %q", tst.in, tst.n, argv)
+	}
+
+	_, err := shelltoken.SplitQuotesN(`"cmd a b`, shelltoken.Whitespace, 2)
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+}
+
+func TestSplitSeqBreak(t *testing.T) {
+	tokens := []string{}
+	for token, err := range shelltoken.SplitSeq(`a b "c d`, shelltoken.Whitespace) {
+		require.NoError(t, err)
+		tokens = append(tokens, token)
+
+		if token == "b" {
+			break
+		}
+	}
+
+	assert.Equal(t, []string{"a", "b"}, tokens)
+}
+
+func TestSplitSeqErrors(t *testing.T) {
+	tokens := []string{}
+	errs := []error{}
+
+	for token, err := range shelltoken.SplitSeq(`a b "c d`, shelltoken.Whitespace) {
+		if err != nil {
+			assert.Empty(t, token)
+			errs = append(errs, err)
+
+			continue
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	assert.Equal(t, []string{"a", "b"}, tokens)
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], `unbalanced " quote opened at position 4`)
+}
+
+func TestSplitFunc(t *testing.T) {
+	tokens := []string{}
+	err := shelltoken.SplitFunc(`a "b c" d`, shelltoken.Whitespace, func(token string) bool {
+		tokens = append(tokens, token)
+
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b c", "d"}, tokens)
+
+	// errors are returned after all complete tokens have been passed
+	tokens = []string{}
+	err = shelltoken.SplitFunc(`a b "c d`, shelltoken.Whitespace, func(token string) bool {
+		tokens = append(tokens, token)
+
+		return true
+	})
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+	assert.Equal(t, []string{"a", "b"}, tokens)
+
+	err = shelltoken.SplitFunc(`a b | c`, shelltoken.Whitespace, func(string) bool { return true }, shelltoken.SplitStopOnShellCharacters)
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+
+	// stopping early skips the rest of the input including its errors
+	tokens = []string{}
+	err = shelltoken.SplitFunc(`a b "c d`, shelltoken.Whitespace, func(token string) bool {
+		tokens = append(tokens, token)
+
+		return token != "a"
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, tokens)
+}
+
+func TestSplitLeading(t *testing.T) {
+	tests := []struct {
+		in   string
+		head string
+		rest string
+	}{
+		{"", "", ""},
+		{"   ", "", ""},
+		{"set", "set", ""},
+		{`set "a b" c d`, "set", `"a b" c d`},
+		{"  key \t value with  spaces ", "key", "value with  spaces "},
+		{`"my key"  'a' \$b`, "my key", `'a' \$b`},
+		{`key "unclosed`, "key", `"unclosed`},
+		{`"unclosed key`, "", `"unclosed key`},
+	}
+
+	for _, tst := range tests {
+		head, rest := shelltoken.SplitLeading(tst.in, shelltoken.Whitespace)
+		assert.Equalf(t, tst.head, head, "head of: %q", tst.in)
+		assert.Equalf(t, tst.rest, rest, "rest of: %q", tst.in)
+	}
+}
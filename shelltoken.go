@@ -9,47 +9,194 @@
 package shelltoken
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+	"unsafe"
+)
+
+var (
+	// ErrShellCharacters matches any ShellCharactersFoundError when used with errors.Is.
+	ErrShellCharacters = errors.New("shell characters found")
+
+	// ErrUnbalancedQuotes matches any UnbalancedQuotesError when used with errors.Is.
+	ErrUnbalancedQuotes = errors.New("unbalanced quotes")
 )
 
 type ShellCharactersFoundError struct {
-	pos int
+	pos      int
+	runePos  int
+	Line     int                    // line of the shell character, starting at 1
+	Col      int                    // column of the shell character in runes, starting at 1
+	Char     rune                   // the shell character
+	Category ShellCharacterCategory // category of the found shell character
 }
 
 func (e *ShellCharactersFoundError) Error() string {
-	return fmt.Sprintf("shell character at position %d", e.pos)
+	if e.Category == ShellCharacterOther {
+		return fmt.Sprintf("shell character %q at line %d column %d (offset %d)", e.Char, e.Line, e.Col, e.pos)
+	}
+
+	return fmt.Sprintf("shell character %q at line %d column %d (offset %d, %s)", e.Char, e.Line, e.Col, e.pos, e.Category)
+}
+
+// Position returns the line and column of the shell character.
+func (e *ShellCharactersFoundError) Position() (line, col int) {
+	return e.Line, e.Col
+}
+
+// Offset returns the byte position of the shell character.
+func (e *ShellCharactersFoundError) Offset() int {
+	return e.pos
+}
+
+// RuneOffset returns the position of the shell character counted in runes.
+func (e *ShellCharactersFoundError) RuneOffset() int {
+	return e.runePos
+}
+
+// Is returns true if target is ErrShellCharacters.
+func (e *ShellCharactersFoundError) Is(target error) bool {
+	return target == ErrShellCharacters
+}
+
+// ShellCharacterCategory classifies the shell character found.
+type ShellCharacterCategory uint8
+
+const (
+	// ShellCharacterOther is any shell character without further classification.
+	ShellCharacterOther ShellCharacterCategory = iota
+
+	// ShellCharacterCommandSubstitution is a $(...) or backtick command substitution.
+	ShellCharacterCommandSubstitution
+
+	// ShellCharacterVariableExpansion is a $VAR or ${VAR} variable expansion.
+	ShellCharacterVariableExpansion
+)
+
+func (c ShellCharacterCategory) String() string {
+	switch c {
+	case ShellCharacterOther:
+		return "other"
+	case ShellCharacterCommandSubstitution:
+		return "command substitution"
+	case ShellCharacterVariableExpansion:
+		return "variable expansion"
+	}
+
+	return fmt.Sprintf("ShellCharacterCategory(%d)", c)
 }
 
-type UnbalancedQuotesError struct{}
+// UnbalancedQuotesError is returned if a quote or raw region is not closed.
+type UnbalancedQuotesError struct {
+	Quote rune // opening quote character, "(" for substitutions, zero for raw regions
+	Pos   int  // position of the opening quote
+}
 
 func (e *UnbalancedQuotesError) Error() string {
-	return "unbalanced quotes"
+	if e.Quote == 0 {
+		return fmt.Sprintf("unbalanced raw region opened at position %d", e.Pos)
+	}
+
+	return fmt.Sprintf("unbalanced %c quote opened at position %d", e.Quote, e.Pos)
+}
+
+// Is returns true if target is ErrUnbalancedQuotes.
+func (e *UnbalancedQuotesError) Is(target error) bool {
+	return target == ErrUnbalancedQuotes
+}
+
+// TooManyTokensError is returned if the input contains more tokens than Config.MaxTokens allows.
+type TooManyTokensError struct {
+	Max int // configured maximum number of tokens
+	Pos int // start position of the first token exceeding the limit
+}
+
+func (e *TooManyTokensError) Error() string {
+	return fmt.Sprintf("more than %d tokens at position %d", e.Max, e.Pos)
+}
+
+// InputTooLongError is returned if the input is longer than Config.MaxInputLen allows.
+type InputTooLongError struct {
+	Max int // configured maximum input length
+	Len int // actual input length
+}
+
+func (e *InputTooLongError) Error() string {
+	return fmt.Sprintf("input length %d exceeds maximum of %d bytes", e.Len, e.Max)
+}
+
+// InvalidCharacterError is returned by SplitRejectNul if the input contains a NUL character.
+type InvalidCharacterError struct {
+	Char rune // the invalid character
+	Pos  int  // position of the character or of the token containing it
+}
+
+func (e *InvalidCharacterError) Error() string {
+	return fmt.Sprintf("invalid character %q at position %d", e.Char, e.Pos)
+}
+
+// MidWordQuoteError is returned by SplitRejectMidWordQuotes if a quote is attached to unquoted characters.
+type MidWordQuoteError struct {
+	Pos int // position of the offending quote
+}
+
+func (e *MidWordQuoteError) Error() string {
+	return fmt.Sprintf("quote within word at position %d", e.Pos)
+}
+
+// UnexpectedQuoteError is returned by SplitStrictQuotes if a quote or escape character
+// appears where no quote is expected.
+type UnexpectedQuoteError struct {
+	Quote rune // offending quote or escape character
+	Pos   int  // position of the offending character
+}
+
+func (e *UnexpectedQuoteError) Error() string {
+	return fmt.Sprintf("unexpected %q at position %d", e.Quote, e.Pos)
 }
 
 const (
 	Whitespace                  = " \t\n\r"
+	DefaultQuotes               = "\"'"
 	DoubleQuoteShellCharacters  = "$`"
 	OutsideQuoteShellCharacters = "$`!&*()~[]|{};<>?"
 )
 
+// typographicQuotes are quotes which are often introduced by copying commands from documents.
+const typographicQuotes = "‘’“”"
+
+// controlOperators are the operators split by SplitOperators.
+var controlOperators = []string{"&&", "||", ";;", ";", "|", "&", "(", ")"}
+
 // SplitOption sets available parse options.
-type SplitOption uint8
+type SplitOption uint64
 
 const (
 	// SplitNoOptions is the zero value for options.
 	SplitNoOptions SplitOption = 0
 
 	// SplitKeepBackslashes: Do not remove backslashes.
+	// Applies to the configured escape character, see Config.WithEscapeChar.
 	SplitKeepBackslashes SplitOption = 1 << iota
 
 	// SplitIgnoreBackslashes does not escape characters by backslash.
+	// Applies to the configured escape character, see Config.WithEscapeChar.
 	SplitIgnoreBackslashes
 
 	// SplitKeepQuotes: Keep quotes in the final argv list.
 	SplitKeepQuotes
 
 	// SplitKeepSeparator: do not remove the split characters. They end up as a separate element in the argv list.
+	// This includes leading and trailing separators, so together with SplitKeepQuotes and SplitKeepBackslashes
+	// joining the elements reproduces the input.
 	SplitKeepSeparator
 
 	// SplitStopOnShellCharacters cancels parsing upon the first shell character and returns ShellCharactersFoundError.
@@ -60,6 +207,136 @@ const (
 
 	// SplitIgnoreShellCharacters will ignore shell characters.
 	SplitIgnoreShellCharacters
+
+	// SplitRejectMidWordQuotes returns MidWordQuoteError if a quote is attached to unquoted characters.
+	// Unlike the sh concatenation, where ab"cd" is the same as abcd, each token must be either fully quoted or bare.
+	SplitRejectMidWordQuotes
+
+	// SplitDoubledQuotes: two quotes of the same kind within a quoted section produce a literal quote, ex.: "a""b" -> a"b.
+	SplitDoubledQuotes
+
+	// SplitStripComments drops everything from an unquoted comment character at the start of a word until the end of the line.
+	// The comment character defaults to "#" and can be changed with Config.CommentChar.
+	SplitStripComments
+
+	// SplitFailOnUndefinedVariables returns UndefinedVariableError for undefined variables instead of expanding them to an empty string.
+	SplitFailOnUndefinedVariables
+
+	// SplitExpandTilde replaces an unquoted leading "~" or "~user" with the home directory returned by Config.HomeDir.
+	SplitExpandTilde
+
+	// SplitOperators splits the control operators "&&", "||", ";;", ";", "|", "&", "(" and ")"
+	// from adjacent words and returns them as separate tokens, see Config.Operators.
+	SplitOperators
+
+	// SplitKeepEmptyFields returns empty tokens between adjacent separators and
+	// for separators at the start or end of the input, ex.: "a,,b" results in "a", "", "b".
+	SplitKeepEmptyFields
+
+	// SplitDecodeEscapes decodes the escape sequences \n, \t, \r, \f, \v and \0 inside double quotes.
+	SplitDecodeEscapes
+
+	// SplitLineContinuation removes a backslash followed by a newline outside of single quotes,
+	// joining both lines into one.
+	SplitLineContinuation
+
+	// SplitStrictPOSIX follows the POSIX shell rules for backslashes: inside double quotes
+	// a backslash only escapes "$", "`", double quotes, backslashes and newlines.
+	// A backslash followed by a newline is removed, also inside double quotes
+	// (implies SplitLineContinuation).
+	SplitStrictPOSIX
+
+	// SplitKeepProcessSubstitution keeps process substitutions like "<(cmd)" and ">(cmd)"
+	// outside of quotes verbatim in the current token instead of reporting shell characters.
+	SplitKeepProcessSubstitution
+
+	// SplitKeepCommandSubstitution keeps command substitutions like "$(cmd)" and "`cmd`"
+	// outside of quotes verbatim in the current token instead of reporting shell characters.
+	SplitKeepCommandSubstitution
+
+	// SplitNoSingleQuotes treats single quotes as regular characters.
+	SplitNoSingleQuotes
+
+	// SplitNoDoubleQuotes treats double quotes as regular characters.
+	SplitNoDoubleQuotes
+
+	// SplitEscapeInSingleQuotes allows backslash escapes inside single quotes, ex.: 'it\'s'.
+	SplitEscapeInSingleQuotes
+
+	// SplitNormalizeNewlines treats an unquoted "\r\n" as a single "\n" separator.
+	SplitNormalizeNewlines
+
+	// SplitHeredoc captures here-documents started by "<<WORD" or "<<-WORD".
+	// The operator, the delimiter word and the body are returned as separate tokens.
+	// The body starts after the current line and ends before the line equal to the
+	// delimiter, "<<-" removes leading tabs from all body lines. Unless the
+	// delimiter is quoted, the body is treated like double quoted text.
+	// The Tokenizer and ScanTokens buffer the input from the first here-document
+	// up to the end of the input.
+	SplitHeredoc
+
+	// SplitFoldSeparators returns a run of consecutive separators as a single
+	// separator token containing the whole run if SplitKeepSeparator is set.
+	SplitFoldSeparators
+
+	// SplitRedirections returns redirections like ">", "2>>", "&>" and "2>&1" as
+	// separate tokens instead of reporting them as shell characters. The file
+	// descriptor prefix is part of the redirection token, the target file is not.
+	SplitRedirections
+
+	// SplitRejectNul returns an InvalidCharacterError if the input or a token
+	// contains a NUL character, which cannot be passed as argument to a process.
+	// This includes NUL characters created by decoding escape sequences.
+	SplitRejectNul
+
+	// SplitTabsAsSpaces converts unquoted and unescaped tabs to spaces before testing
+	// for separators, so SplitKeepSeparator returns spaces instead of tabs.
+	// Tabs inside quotes are preserved.
+	SplitTabsAsSpaces
+
+	// SplitStrictQuotes returns an UnexpectedQuoteError for quoting which is valid but most
+	// likely a mistake, ex.: from copy and paste. This affects the following cases:
+	// - a quote opening directly after a closing quote of the same kind, ex.: "a""b".
+	// - unquoted and unescaped typographic quotes, ex.: “a”, unless they are configured quotes.
+	// - an escape character at the end of the input which has nothing to escape.
+	SplitStrictQuotes
+
+	// SplitKeepRedundantBackslashes keeps an unquoted backslash literally if it does not
+	// precede a special character, ex.: a\b stays a\b while a\ b results in "a b".
+	// Special characters are separators, quotes, the escape character itself, shell
+	// characters, the comment character and newlines. A trailing backslash is kept as well.
+	SplitKeepRedundantBackslashes
+
+	// SplitCheckEnvValues returns a ShellCharactersFoundError if the value of a leading
+	// environment assignment contains shell characters, ex.: A="$(id)" cmd, even if
+	// shell characters are ignored otherwise. Arguments after the command are not affected.
+	SplitCheckEnvValues
+
+	// SplitTrimTokens removes unquoted leading and trailing whitespace from tokens, which is
+	// useful with non-whitespace separators, ex.: a , " b " split by "," results in "a" and " b ".
+	// Tokens consisting of unquoted whitespace only are dropped.
+	SplitTrimTokens
+
+	// SplitFlagHistoryExpansion reports an unquoted "!" as shell character. History expansion
+	// is disabled in non-interactive shells, so "!" is a regular character by default.
+	SplitFlagHistoryExpansion
+
+	// SplitSmartQuotes treats the typographic quotes “ and ” like double quotes and ‘ and ’
+	// like single quotes, ex.: “a b” results in "a b". Either quote of a kind can open and
+	// close the quoted text, typographic quotes within ascii quotes are kept literally.
+	// Note that ’ is also used as apostrophe, ex.: don’t.
+	SplitSmartQuotes
+)
+
+const (
+	// linuxOptions are the options used by SplitLinux.
+	linuxOptions = SplitStopOnShellCharacters
+
+	// windowsOptions are the options used by SplitWindows.
+	windowsOptions = SplitKeepBackslashes | SplitIgnoreBackslashes | SplitStopOnShellCharacters | SplitDoubledQuotes
+
+	// powerShellOptions are the options used by SplitPowerShell.
+	powerShellOptions = SplitStopOnShellCharacters | SplitDoubledQuotes
 )
 
 // SplitLinux will tokenize a string the way the linux /bin/sh would do.
@@ -76,20 +353,36 @@ const (
 // - keep separator: false.
 // returns error if shell characters were found.
 func SplitLinux(str string) (env, argv []string, err error) {
-	argv, err = SplitQuotes(strings.TrimSpace(str), Whitespace, SplitStopOnShellCharacters)
+	argv, err = SplitQuotes(strings.TrimSpace(str), Whitespace, linuxOptions)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	env, argv = ExtractEnvFromArgv(argv)
 	if len(argv) == 0 {
-		argv = append(argv, "")
+		// input consisting only of assignments has an empty command
+		argv = []string{""}
 	}
 
-	env, argv = ExtractEnvFromArgv(argv)
-
 	return env, argv, nil
 }
 
+// SplitLinuxNoEnv works like SplitLinux but does not extract environment
+// assignments. All tokens are returned as argv and env is always empty, so
+// a leading token containing "=" stays the command.
+func SplitLinuxNoEnv(str string) (env, argv []string, err error) {
+	argv, err = SplitQuotes(strings.TrimSpace(str), Whitespace, linuxOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(argv) == 0 {
+		argv = append(argv, "")
+	}
+
+	return []string{}, argv, nil
+}
+
 // SplitWindows will tokenize a string the way windows would do.
 // A successful parse will return the env list with
 // parsed environment variable definitions along with
@@ -102,140 +395,90 @@ func SplitLinux(str string) (env, argv []string, err error) {
 // - keep backslashes: true.
 // - keep quotes: false.
 // - keep separator: false.
+// - doubled quotes: true.
 // returns error if shell characters were found.
 func SplitWindows(str string) (env, argv []string, err error) {
-	windowsOptions := SplitKeepBackslashes | SplitIgnoreBackslashes | SplitStopOnShellCharacters
-
 	argv, err = SplitQuotes(strings.TrimSpace(str), Whitespace, windowsOptions)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	env, argv = ExtractEnvFromArgv(argv)
 	if len(argv) == 0 {
-		argv = append(argv, "")
+		// input consisting only of assignments has an empty command
+		argv = []string{""}
 	}
 
-	env, argv = ExtractEnvFromArgv(argv)
-
 	return env, argv, nil
 }
 
 // ExtractEnvFromArgv splits list of arguments into env and args.
+// Leading arguments are environment assignments if the name before the first "="
+// is a valid shell identifier, the first other argument starts the command.
+// Appending assignments like "PATH+=:/opt/bin" are environment assignments as well.
+// An "export" keyword followed by an assignment is skipped, ex.:
+// "export A=1 B=2 cmd" results in the env "A=1", "B=2" and the command "cmd".
+// If argv consists of assignments only, args is empty.
 func ExtractEnvFromArgv(argv []string) (envs, args []string) {
-	for i := range argv {
-		if !strings.Contains(argv[i], "=") {
-			return argv[0:i], argv[i:]
-		}
-	}
-
-	return
+	return ExtractEnvFromArgvFunc(argv, isEnvAssignment)
 }
 
-// SplitQuotes will tokenize text into chunks honoring quotes.
-// Options are a list of SplitOption(s) or a bitmask of SplitOption(s)
-// An unsuccessful parse will return an error. The error will be either
-// UnbalancedQuotesError or ShellCharactersFoundError.
-func SplitQuotes(str, sep string, options ...SplitOption) (argv []string, err error) {
-	pst := newParseState(options)
-	argv = []string{}
-
-	for pos, char := range str {
-		if pst.stopShell && pst.firstShellPos != -1 {
-			return nil, &ShellCharactersFoundError{pos: pst.firstShellPos}
-		}
-
-		switch {
-		case pst.escaped:
-			// reset escaped flag
-			pst.escaped = false
-			pst.addToken(char, pos)
-		case char == '\\':
-			if !pst.ignBackslashes {
-				pst.escaped = true
-			}
-
-			switch {
-			case pst.keepBackSlash, pst.inSingleQuotes:
-				// backslashes are kept in single quotes
-				pst.addToken(char, pos)
-			case pst.inDoubleQuotes:
-				// or in double quotes except...
-				if len(str) > pos {
-					switch str[pos+1] {
-					// next character is a double quote again
-					case '"':
-					// or a backslash
-					case '\\':
-					default:
-						pst.addToken(char, pos)
-					}
-				}
-			}
+// ExtractEnvFromArgvFunc works like ExtractEnvFromArgv but uses isEnv to decide
+// whether an argument is an environment assignment, ex.: to require uppercase names.
+func ExtractEnvFromArgvFunc(argv []string, isEnv func(arg string) bool) (envs, args []string) {
+	envIndex, cmd := extractEnvIndexFunc(argv, isEnv)
 
-		case char == '"':
-			pst.hasToken = true
+	if len(envIndex) == cmd {
+		return argv[0:cmd], argv[cmd:]
+	}
 
-			if !pst.inSingleQuotes {
-				pst.inDoubleQuotes = !pst.inDoubleQuotes
-				if pst.keepQuote {
-					pst.addToken(char, pos)
-				}
-			} else {
-				pst.addToken(char, pos)
-			}
-		case char == '\'':
-			pst.hasToken = true
+	envs = make([]string, 0, len(envIndex))
+	for _, i := range envIndex {
+		envs = append(envs, argv[i])
+	}
 
-			if !pst.inDoubleQuotes {
-				pst.inSingleQuotes = !pst.inSingleQuotes
-				if pst.keepQuote {
-					pst.addToken(char, pos)
-				}
-			} else {
-				pst.addToken(char, pos)
-			}
-		case strings.ContainsRune(sep, char):
-			switch {
-			case pst.inSingleQuotes, pst.inDoubleQuotes:
-				pst.addToken(char, pos)
-			case pst.keepSep:
-				if pst.hasToken {
-					argv = append(argv, pst.token.String())
-					pst.token.Reset()
-
-					pst.hasToken = false
-				}
+	return envs, argv[cmd:]
+}
 
-				argv = append(argv, string(char))
-			case pst.hasToken:
-				argv = append(argv, pst.token.String())
-				pst.token.Reset()
+// extractEnvIndex returns the indexes of the leading environment assignments in argv
+// and the index of the command, which is len(argv) if there is no command.
+func extractEnvIndex(argv []string) (envIndex []int, cmd int) {
+	return extractEnvIndexFunc(argv, isEnvAssignment)
+}
 
-				pst.hasToken = false
-			}
+// extractEnvIndexFunc works like extractEnvIndex using isEnv to detect assignments.
+func extractEnvIndexFunc(argv []string, isEnv func(arg string) bool) (envIndex []int, cmd int) {
+	for cmd = 0; cmd < len(argv); cmd++ {
+		switch {
+		case isEnv(argv[cmd]):
+			envIndex = append(envIndex, cmd)
+		case argv[cmd] == "export" && cmd+1 < len(argv) && isEnv(argv[cmd+1]):
 		default:
-			pst.addToken(char, pos)
+			return envIndex, cmd
 		}
 	}
 
-	// in case the last character was a shell char
-	if pst.stopShell && pst.firstShellPos != -1 {
-		return nil, &ShellCharactersFoundError{pos: pst.firstShellPos}
-	}
+	return envIndex, cmd
+}
 
-	// append last token
-	if pst.hasToken {
-		argv = append(argv, pst.token.String())
-	}
+// isEnvAssignment returns true if arg assigns or appends ("KEY+=VALUE") a value to a valid variable name.
+func isEnvAssignment(arg string) bool {
+	key, _, ok := strings.Cut(arg, "=")
 
-	switch {
-	case pst.inSingleQuotes, pst.inDoubleQuotes:
-		return nil, &UnbalancedQuotesError{}
-	case pst.contShell && pst.firstShellPos != -1:
-		return argv, &ShellCharactersFoundError{pos: pst.firstShellPos}
-	default:
-		return argv, nil
-	}
+	return ok && isValidEnvKey(strings.TrimSuffix(key, "+"))
+}
+
+// SplitQuotes will tokenize text into chunks honoring quotes.
+// Options are a list of SplitOption(s) or a bitmask of SplitOption(s)
+// An unsuccessful parse will return an error. The error will be either
+// UnbalancedQuotesError or ShellCharactersFoundError.
+// A trailing backslash outside of quotes escapes nothing and is dropped, a
+// trailing backslash inside double quotes is kept (and the string ends with
+// unbalanced quotes).
+func SplitQuotes(str, sep string, options ...SplitOption) (argv []string, err error) {
+	cfg := NewConfig(sep, options...)
+
+	return cfg.Split(str)
 }
 
 type parseState struct {
@@ -245,76 +488,1316 @@ type parseState struct {
 	inSingleQuotes bool
 	inDoubleQuotes bool
 	firstShellPos  int // position of first shell character found
-	token          strings.Builder
+	firstShellCat  ShellCharacterCategory
+	firstShellChar rune
+	str            string // input string for lookaheads
+	token          bytes.Buffer
+	tokenStart     int // position of the first character of the current token
+	quoteEnd       int // position of the last closing quote if directly before the current character
+	emit           func(token string, start, end int) bool
+	stopped        bool  // emit requested to stop parsing
+	limitErr       error // set if parsing stopped because of a limit or an invalid token
+	borrowTokens   bool  // emitted tokens are only valid until emit returns
+	numTokens      int
+	partial        bool // str is not the complete input, do not finish at the end of str
+	resume         int  // position to continue parsing from with parsePartial
+	lastField      bool // the last emitted token is a field ending at the following separator
+	afterField     bool // str starts with the separator ending a field emitted before, see Tokenizer
+	skip           int  // characters before this position have already been consumed
 	// parse flags
+	sep            string
+	sepFunc        func(rune) bool
+	delim          string // multi character separator used by SplitQuotesString
+	rawStart       string
+	rawEnd         string
+	operators      []string // sorted by length, longest first
 	keepBackSlash  bool
 	keepQuote      bool
 	keepSep        bool
+	keepEmpty      bool
+	decodeEscapes  bool
+	lineCont       bool
+	strictPOSIX    bool
+	fish           bool // fish shell escape rules
+	zsh            bool // zsh process substitution and ANSI-C quoting
+	cmd            bool // cmd.exe rules, the escape character is literal inside double quotes
+	heredoc        bool
+	heredocs       []heredoc // here-documents waiting for the end of the line
+	keepProcSubst  bool
+	keepCmdSubst   bool
+	escapeSingle   bool
+	normalizeNL    bool
+	foldSep        bool
+	redirections   bool
+	keepComments   bool // emit comments as tokens instead of stripping them
+	rejectNul      bool
+	tabsAsSpaces   bool
+	strictQuotes   bool
+	keepRedundant  bool
+	checkEnv       bool
+	trimTokens     bool
+	flagHistory    bool
+	smartQuotes    bool
+	smartStart     int             // position of the last opening typographic quote
+	padding        int             // number of bytes of unquoted whitespace at the end of the token
+	envPhase       bool            // tokens emitted so far are leading env assignments
+	quoteClosed    int             // position after the last closing quote, used by SplitStrictQuotes
+	ctx            context.Context // checked periodically if set
+	ctxCheck       int             // position of the next context check
+	recordSpans    bool
+	spans          []Span
+	escapeStart    int // position of the escape character of the current escape sequence
 	stopShell      bool
 	contShell      bool
 	ignShell       bool
 	ignBackslashes bool
+	rejectMidQuote bool
+	doubledQuotes  bool
+	stripComments  bool
+	commentChar    rune
+	lookup         func(name string) (string, bool)
+	failUndefined  bool
+	expandTilde    bool
+	homeDir        func(user string) (string, bool)
+	quotes         string
+	quoteChar      rune // quote character of the current quoted section
+	quoteStart     int  // position of the opening quote of the current quoted section
+	escapeChar     rune
+	maxTokens      int
+	maxInputLen    int
+	outsideShell   string
+	doubleShell    string
+	special        asciiSet // ascii characters which need more than being added to the current token
+	quotedSpecial  asciiSet // same as special but inside quotes
+	sepSet         asciiSet // ascii characters of sep
+	quoteSet       asciiSet // ascii characters of quotes
+	noFastPath     bool     // the separators are not known in advance, see SeparatorFunc
+}
+
+func newParseState(cfg *Config) *parseState {
+	pst := &parseState{}
+	pst.init(cfg)
+
+	return pst
+}
+
+// maxPooledTokenBuffer is the largest token buffer kept in the pool of parse states.
+const maxPooledTokenBuffer = 64 * 1024
+
+// parseStates caches parse states between calls of Config.Split.
+var parseStates = sync.Pool{
+	New: func() any { return &parseState{} },
+}
+
+// getParseState returns a parse state from the pool set up for cfg.
+func getParseState(cfg *Config) *parseState {
+	pst, _ := parseStates.Get().(*parseState)
+	pst.init(cfg)
+
+	return pst
+}
+
+// putParseState returns pst to the pool, it must not be used afterwards.
+// All references to the input and the settings are cleared.
+func putParseState(pst *parseState) {
+	if pst.token.Cap() > maxPooledTokenBuffer {
+		return
+	}
+
+	pst.token.Reset()
+	*pst = parseState{token: pst.token}
+	parseStates.Put(pst)
 }
 
-func newParseState(options []SplitOption) *parseState {
-	pst := &parseState{
+// init sets up the state to parse with the settings from cfg.
+// The token buffer is kept to reuse its memory.
+func (p *parseState) init(cfg *Config) {
+	p.token.Reset()
+
+	*p = parseState{
 		hasToken:       false,
 		escaped:        false,
 		inSingleQuotes: false,
 		inDoubleQuotes: false,
-		token:          strings.Builder{},
+		token:          p.token,
+		tokenStart:     -1,
+		quoteEnd:       -1,
+		quoteClosed:    -1,
+		smartStart:     -1,
 		firstShellPos:  -1,
 		keepBackSlash:  false,
 		keepQuote:      false,
 		keepSep:        false,
+		keepEmpty:      false,
+		decodeEscapes:  false,
+		lineCont:       false,
+		strictPOSIX:    false,
+		fish:           false,
+		zsh:            false,
+		keepProcSubst:  false,
+		keepCmdSubst:   false,
 		stopShell:      false,
 		contShell:      false,
 		ignShell:       false,
 		ignBackslashes: false,
+		rejectMidQuote: false,
+		doubledQuotes:  false,
+		stripComments:  false,
+		commentChar:    cfg.CommentChar,
+		lookup:         cfg.Lookup,
+		failUndefined:  false,
+		expandTilde:    false,
+		homeDir:        cfg.HomeDir,
+		quotes:         cfg.Quotes,
+		quoteChar:      0,
+		quoteStart:     -1,
+		escapeStart:    -1,
+		escapeChar:     cfg.EscapeChar,
+		maxTokens:      cfg.MaxTokens,
+		maxInputLen:    cfg.MaxInputLen,
+		outsideShell:   cfg.OutsideQuoteShellCharacters,
+		doubleShell:    cfg.DoubleQuoteShellCharacters,
+		sep:            cfg.Separators,
+		sepFunc:        cfg.SeparatorFunc,
+		rawStart:       cfg.RawStart,
+		rawEnd:         cfg.RawEnd,
+		operators:      sortOperators(cfg.Operators),
 	}
 
-	option := SplitNoOptions
-	for _, o := range options {
-		option |= o
-		if o == SplitNoOptions {
-			option = SplitNoOptions
+	if p.rawEnd == "" {
+		p.rawStart = ""
+	}
+
+	option := cfg.Options
+
+	p.keepBackSlash = option&SplitKeepBackslashes > 0
+	p.keepQuote = option&SplitKeepQuotes > 0
+	p.keepSep = option&SplitKeepSeparator > 0
+	p.keepEmpty = option&SplitKeepEmptyFields > 0
+	p.decodeEscapes = option&SplitDecodeEscapes > 0
+	p.strictPOSIX = option&SplitStrictPOSIX > 0
+	p.keepProcSubst = option&SplitKeepProcessSubstitution > 0
+	p.keepCmdSubst = option&SplitKeepCommandSubstitution > 0
+	p.escapeSingle = option&SplitEscapeInSingleQuotes > 0
+	p.normalizeNL = option&SplitNormalizeNewlines > 0
+	p.heredoc = option&SplitHeredoc > 0
+	p.foldSep = option&SplitFoldSeparators > 0
+	p.redirections = option&SplitRedirections > 0
+	p.rejectNul = option&SplitRejectNul > 0
+	p.tabsAsSpaces = option&SplitTabsAsSpaces > 0
+	p.strictQuotes = option&SplitStrictQuotes > 0
+	p.keepRedundant = option&SplitKeepRedundantBackslashes > 0
+	p.checkEnv = option&SplitCheckEnvValues > 0
+	p.trimTokens = option&SplitTrimTokens > 0
+	p.flagHistory = option&SplitFlagHistoryExpansion > 0
+	p.smartQuotes = option&SplitSmartQuotes > 0
+	p.envPhase = true
+
+	if option&SplitNoSingleQuotes > 0 {
+		p.quotes = strings.ReplaceAll(p.quotes, "'", "")
+	}
+
+	if option&SplitNoDoubleQuotes > 0 {
+		p.quotes = strings.ReplaceAll(p.quotes, `"`, "")
+	}
+	p.lineCont = option&SplitLineContinuation > 0 || p.strictPOSIX
+	p.stopShell = option&SplitStopOnShellCharacters > 0
+	p.contShell = option&SplitContinueOnShellCharacters > 0
+	p.ignBackslashes = option&SplitIgnoreBackslashes > 0
+	p.rejectMidQuote = option&SplitRejectMidWordQuotes > 0
+	p.doubledQuotes = option&SplitDoubledQuotes > 0
+	p.stripComments = option&SplitStripComments > 0
+	p.failUndefined = option&SplitFailOnUndefinedVariables > 0
+	p.expandTilde = option&SplitExpandTilde > 0 && p.homeDir != nil
+
+	if option&SplitOperators > 0 {
+		p.operators = sortOperators(append(slices.Clone(cfg.Operators), controlOperators...))
+	}
+
+	if p.commentChar == 0 {
+		p.commentChar = '#'
+	}
+
+	if p.outsideShell == "" {
+		p.outsideShell = OutsideQuoteShellCharacters
+	}
+
+	if p.doubleShell == "" {
+		p.doubleShell = DoubleQuoteShellCharacters
+	}
+
+	// other quote characters are literal inside double quotes
+	for _, quote := range p.quotes {
+		if strings.ContainsRune(p.doubleShell, quote) {
+			p.doubleShell = strings.ReplaceAll(p.doubleShell, string(quote), "")
 		}
 	}
 
-	pst.keepBackSlash = option&SplitKeepBackslashes > 0
-	pst.keepQuote = option&SplitKeepQuotes > 0
-	pst.keepSep = option&SplitKeepSeparator > 0
-	pst.stopShell = option&SplitStopOnShellCharacters > 0
-	pst.contShell = option&SplitContinueOnShellCharacters > 0
-	pst.ignBackslashes = option&SplitIgnoreBackslashes > 0
-	pst.ignShell = (!pst.stopShell && !pst.contShell) || option&SplitIgnoreShellCharacters > 0
+	p.ignShell = (!p.stopShell && !p.contShell) || option&SplitIgnoreShellCharacters > 0
+	p.noFastPath = p.sepFunc != nil
+	p.sepSet = newASCIISet(p.sep)
+	p.quoteSet = newASCIISet(p.quotes)
+	p.special = p.specialCharacters()
+	p.quotedSpecial = p.quotedSpecialCharacters()
+}
+
+// specialCharacters returns the set of ascii characters which might have a special
+// meaning with the current options. All other ascii characters are added to the
+// current token without further checks.
+func (p *parseState) specialCharacters() asciiSet {
+	set := specialASCII
 
-	return pst
+	set.addAll(p.sep)
+	set.addAll(p.quotes)
+	set.add(p.escapeChar)
+	set.add(p.commentChar)
+
+	if p.outsideShell != OutsideQuoteShellCharacters {
+		set.addAll(p.outsideShell)
+	}
+
+	if p.doubleShell != DoubleQuoteShellCharacters {
+		set.addAll(p.doubleShell)
+	}
+
+	if p.trimTokens {
+		set.addAll(" \t\n\v\f\r")
+	}
+
+	for _, prefix := range [2]string{p.delim, p.rawStart} {
+		if prefix != "" {
+			set.add(rune(prefix[0]))
+		}
+	}
+
+	for _, op := range p.operators {
+		set.add(rune(op[0]))
+	}
+
+	return set
 }
 
-func (p *parseState) addToken(char rune, pos int) {
-	p.hasToken = true
+// quotedSpecialCharacters returns the set of ascii characters which might have a
+// special meaning inside quotes. Separators and most shell characters are literal there.
+func (p *parseState) quotedSpecialCharacters() asciiSet {
+	set := newASCIISet(p.quotes)
 
-	// exit early if we do not search for shell characters (anymore)
+	set.addAll(p.doubleShell)
+	set.addAll("\\$`")
+	set.add(p.escapeChar)
+
+	return set
+}
+
+// sortOperators returns a copy of the operators sorted by length, longest first.
+func sortOperators(operators []string) []string {
+	if len(operators) == 0 {
+		return nil
+	}
+
+	sorted := make([]string, 0, len(operators))
+	for _, op := range operators {
+		if op != "" {
+			sorted = append(sorted, op)
+		}
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+
+	return sorted
+}
+
+// isCRLF returns true if char starts an unquoted "\r\n" which should be
+// treated as a single newline separator.
+func (p *parseState) isCRLF(char rune, pos int) bool {
+	return p.normalizeNL && char == '\r' && !p.inSingleQuotes && !p.inDoubleQuotes &&
+		p.isSeparator('\n') && p.nextRune(pos) == '\n'
+}
+
+// isUnquotedTab returns true if char is a tab which should be converted to a space.
+func (p *parseState) isUnquotedTab(char rune) bool {
+	return p.tabsAsSpaces && char == '\t' && !p.escaped && !p.inSingleQuotes && !p.inDoubleQuotes
+}
+
+// isRedundantEscape returns true if char is an unquoted escape character which
+// does not precede a special character and should be kept by SplitKeepRedundantBackslashes.
+func (p *parseState) isRedundantEscape(char rune, pos int) bool {
+	if !p.keepRedundant || char != p.escapeChar || p.escapeChar == 0 || p.ignBackslashes || p.inSingleQuotes || p.inDoubleQuotes {
+		return false
+	}
+
+	next := p.nextRune(pos)
 	switch {
-	case p.ignShell, p.inSingleQuotes, p.firstShellPos != -1:
-		p.token.WriteRune(char)
+	case next == p.escapeChar, next == '\n', next == p.commentChar:
+		return false
+	case p.isSeparator(next), p.isQuote(next), strings.ContainsRune(p.outsideShell, next):
+		return false
+	}
+
+	return true
+}
+
+// isPadding returns true if char is unquoted whitespace which is trimmed by SplitTrimTokens.
+func (p *parseState) isPadding(char rune) bool {
+	return p.trimTokens && !p.inSingleQuotes && !p.inDoubleQuotes && unicode.IsSpace(char)
+}
 
+// addPadding adds unquoted whitespace to the current token, it is removed again
+// if nothing else follows. Leading whitespace is skipped.
+func (p *parseState) addPadding(char rune) {
+	if !p.hasToken {
 		return
 	}
 
+	p.token.WriteRune(char)
+	p.padding += utf8.RuneLen(char)
+}
+
+// smartQuote returns the ascii quote for a typographic quote which opens or closes
+// quoted text with SplitSmartQuotes, other characters are returned unchanged.
+// Quoted text opened by a typographic quote must be closed by one of the same kind.
+func (p *parseState) smartQuote(char rune, pos int) rune {
+	var quote rune
+	var inQuotes bool
+
+	switch char {
+	case '“', '”':
+		quote, inQuotes = '"', p.inDoubleQuotes
+	case '‘', '’':
+		quote, inQuotes = '\'', p.inSingleQuotes
+	default:
+		return char
+	}
+
 	switch {
-	case p.inDoubleQuotes:
-		if strings.ContainsRune(DoubleQuoteShellCharacters, char) {
-			p.firstShellPos = pos
-		}
-	case strings.ContainsRune(OutsideQuoteShellCharacters, char):
-		p.firstShellPos = pos
-	case char == '\\':
-		if !p.keepBackSlash && !p.ignBackslashes {
-			p.firstShellPos = pos
-		}
+	case !p.isQuote(quote):
+		return char
+	case !p.inSingleQuotes && !p.inDoubleQuotes:
+		p.smartStart = pos
+	case !inQuotes || p.quoteStart != p.smartStart:
+		// literal inside other quotes
+		return char
 	}
 
-	p.token.WriteRune(char)
+	return quote
+}
+
+// isLineContinuation returns true if char is the escape character followed by a newline outside of single quotes.
+func (p *parseState) isLineContinuation(char rune, pos int) bool {
+	return p.lineCont && char == p.escapeChar && p.escapeChar != 0 && !p.inSingleQuotes && p.nextRune(pos) == '\n'
+}
+
+// decodeEscape returns the control character for an escaped char inside double quotes
+// if SplitDecodeEscapes is set, otherwise char is returned unchanged.
+func (p *parseState) decodeEscape(char rune) rune {
+	if !p.decodeEscapes || !p.inDoubleQuotes {
+		return char
+	}
+
+	switch char {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	case 'f':
+		return '\f'
+	case 'v':
+		return '\v'
+	case '0':
+		return 0
+	}
+
+	return char
+}
+
+// isSeparator returns true if char separates tokens.
+func (p *parseState) isSeparator(char rune) bool {
+	if p.sepFunc != nil {
+		return p.sepFunc(char)
+	}
+
+	if char < utf8.RuneSelf {
+		return p.sepSet.contains(char)
+	}
+
+	return strings.ContainsRune(p.sep, char)
+}
+
+// isSpecial returns true if the ascii char might have a special meaning at the current position.
+func (p *parseState) isSpecial(char rune) bool {
+	if p.inSingleQuotes || p.inDoubleQuotes {
+		return p.quotedSpecial.contains(char)
+	}
+
+	return p.special.contains(char)
+}
+
+// isQuote returns true if char is one of the quote characters.
+func (p *parseState) isQuote(char rune) bool {
+	if char < utf8.RuneSelf {
+		return p.quoteSet.contains(char)
+	}
+
+	return strings.ContainsRune(p.quotes, char)
+}
+
+// separatorRunEnd returns the position after the run of separators starting at pos.
+// The run ends after a newline if here-documents are pending, their bodies start there.
+func (p *parseState) separatorRunEnd(pos int) int {
+	end := pos
+	for end < len(p.str) {
+		char, size := utf8.DecodeRuneInString(p.str[end:])
+		if p.tabsAsSpaces && char == '\t' {
+			char = ' '
+		}
+
+		if !p.isSeparator(char) {
+			break
+		}
+
+		end += size
+
+		if char == '\n' && len(p.heredocs) > 0 {
+			break
+		}
+	}
+
+	return end
+}
+
+// separate finishes the current token at an unquoted separator sep which spans from pos to end.
+func (p *parseState) separate(sep string, pos, end int) {
+	p.emitEmptyField(pos)
+	p.flushToken(pos)
+	if p.keepSep {
+		p.emitToken(sep, pos, end)
+	}
+
+	p.skip = end
+}
+
+// emitEmptyField emits an empty field with SplitKeepEmptyFields if no token
+// precedes the separator at pos.
+func (p *parseState) emitEmptyField(pos int) {
+	if !p.keepEmpty || p.hasToken || (pos == 0 && p.afterField) {
+		return
+	}
+
+	p.emitToken("", pos, pos)
+	p.lastField = true
+}
+
+// endsWithSeparator returns true if the input ends with a separator.
+func (p *parseState) endsWithSeparator() bool {
+	if p.delim != "" {
+		return strings.HasSuffix(p.str, p.delim)
+	}
+
+	char, _ := utf8.DecodeLastRuneInString(p.str)
+
+	return p.str != "" && p.isSeparator(char)
+}
+
+// reset clears the current state so another string can be parsed with the same settings.
+// The token buffer is kept to reuse its memory.
+func (p *parseState) reset() {
+	p.hasToken = false
+	p.escaped = false
+	p.inSingleQuotes = false
+	p.inDoubleQuotes = false
+	p.firstShellPos = -1
+	p.firstShellCat = ShellCharacterOther
+	p.firstShellChar = 0
+	p.str = ""
+	p.token.Reset()
+	p.tokenStart = -1
+	p.quoteEnd = -1
+	p.quoteClosed = -1
+	p.smartStart = -1
+	p.emit = nil
+	p.stopped = false
+	p.limitErr = nil
+	p.numTokens = 0
+	p.skip = 0
+	p.resume = 0
+	p.lastField = false
+	p.afterField = false
+	p.quoteChar = 0
+	p.quoteStart = -1
+	p.escapeStart = -1
+	p.spans = nil
+	p.heredocs = nil
+	p.ctxCheck = 0
+	p.envPhase = true
+	p.padding = 0
+}
+
+// combineOptions merges a list of options into a single bitmask.
+// SplitNoOptions resets all previous options.
+func combineOptions(options []SplitOption) SplitOption {
+	option := SplitNoOptions
+	for _, o := range options {
+		option |= o
+		if o == SplitNoOptions {
+			option = SplitNoOptions
+		}
+	}
+
+	return option
+}
+
+// parse tokenizes str and calls emit for each completed token along with its
+// start and end byte offset in str. Parsing stops if emit returns false.
+func (p *parseState) parse(str string, emit func(token string, start, end int) bool) error {
+	if p.maxInputLen > 0 && len(str) > p.maxInputLen {
+		return &InputTooLongError{Max: p.maxInputLen, Len: len(str)}
+	}
+
+	if p.rejectNul {
+		if pos := strings.IndexByte(str, 0); pos != -1 {
+			return &InvalidCharacterError{Char: 0, Pos: pos}
+		}
+	}
+
+	p.emit = emit
+	p.str = str
+
+	if err := p.scan(0, len(str)); err != nil {
+		return err
+	}
+
+	if p.stopped || p.partial {
+		return p.limitErr
+	}
+
+	// in case the last character was a shell char
+	if p.stopShell && p.firstShellPos != -1 {
+		return p.shellError()
+	}
+
+	if p.inSingleQuotes || p.inDoubleQuotes {
+		return &UnbalancedQuotesError{Quote: p.quoteChar, Pos: p.quoteStart}
+	}
+
+	if p.strictQuotes && p.escaped {
+		return &UnexpectedQuoteError{Quote: p.escapeChar, Pos: p.escapeStart}
+	}
+
+	// append last token or empty field after a trailing separator
+	if p.keepEmpty && !p.hasToken && p.endsWithSeparator() {
+		p.emitToken("", len(str), len(str))
+	}
+
+	p.flushToken(len(str))
+
+	if len(p.heredocs) > 0 {
+		if err := p.addHeredocBodies(len(str)); err != nil {
+			return err
+		}
+	}
+
+	if p.limitErr != nil {
+		return p.limitErr
+	}
+
+	if p.contShell && p.firstShellPos != -1 {
+		return p.shellError()
+	}
+
+	return nil
+}
+
+// parsePartial continues parsing str, which extends the input of the previous call,
+// at the position the previous call stopped. Only characters followed by at least
+// lookahead bytes are parsed, so the result does not depend on input which is not
+// available yet. The parse state must be partial.
+func (p *parseState) parsePartial(str string, lookahead int, emit func(token string, start, end int) bool) error {
+	if p.maxInputLen > 0 && len(str) > p.maxInputLen {
+		return &InputTooLongError{Max: p.maxInputLen, Len: len(str)}
+	}
+
+	if p.rejectNul {
+		if pos := strings.IndexByte(str[p.resume:], 0); pos != -1 {
+			return &InvalidCharacterError{Char: 0, Pos: p.resume + pos}
+		}
+	}
+
+	p.emit = emit
+	p.str = str
+
+	if err := p.scan(p.resume, len(str)-lookahead); err != nil {
+		return err
+	}
+
+	return p.limitErr
+}
+
+// scan parses the characters of p.str from the position from up to the position limit.
+// The position to continue from is stored in p.resume.
+func (p *parseState) scan(from, limit int) error {
+	str := p.str
+
+	for pos, char := range str[from:] {
+		pos += from
+		if pos >= limit {
+			p.resume = pos
+
+			return nil
+		}
+
+		if pos < p.skip {
+			continue
+		}
+
+		if p.stopped {
+			return p.limitErr
+		}
+
+		if p.ctx != nil {
+			if err := p.checkContext(pos); err != nil {
+				return err
+			}
+		}
+
+		if p.stopShell && p.firstShellPos != -1 {
+			return p.shellError()
+		}
+
+		if char < utf8.RuneSelf && !p.escaped && p.quoteEnd == -1 && !p.noFastPath && !p.isSpecial(char) {
+			// plain character without special meaning, see addToken
+			p.markToken(pos)
+			p.hasToken = true
+			p.padding = 0
+			p.token.WriteByte(byte(char))
+
+			continue
+		}
+
+		if p.isUnquotedTab(char) {
+			char = ' '
+		}
+
+		if p.smartQuotes && !p.escaped {
+			char = p.smartQuote(char, pos)
+		}
+
+		operator := p.matchOperator(pos)
+		redirection, redirectionStart := p.matchRedirection(char, pos)
+
+		if p.quoteEnd != -1 {
+			if !p.isSeparator(char) && !p.isDelimiter(pos) && operator == "" && redirection == "" {
+				return &MidWordQuoteError{Pos: p.quoteEnd}
+			}
+
+			p.quoteEnd = -1
+		}
+
+		switch {
+		case p.escaped:
+			// reset escaped flag
+			p.escaped = false
+			p.addSpan(SpanEscape, p.escapeStart, p.runeEnd(pos))
+
+			if p.fish {
+				// fish has no special meaning for escaped characters
+				p.addEscaped(p.decodeEscape(char), pos)
+			} else {
+				p.addToken(p.decodeEscape(char), pos)
+			}
+		case p.isRawStart(str, pos):
+			if !p.addRawRegion(str, pos) {
+				if p.partial {
+					// end delimiter might follow later
+					p.resume = pos
+
+					return nil
+				}
+
+				return &UnbalancedQuotesError{Pos: pos}
+			}
+		case p.isProcessSubstitution(char, pos):
+			if !p.addSubstitution(pos, pos+2) {
+				if p.partial {
+					// closing parenthesis might follow later
+					p.resume = pos
+
+					return nil
+				}
+
+				return &UnbalancedQuotesError{Quote: '(', Pos: pos}
+			}
+		case p.isCommandSubstitution(char, pos):
+			if !p.addCommandSubstitution(char, pos) {
+				if p.partial {
+					// closing delimiter might follow later
+					p.resume = pos
+
+					return nil
+				}
+
+				if char == '$' {
+					char = '('
+				}
+
+				return &UnbalancedQuotesError{Quote: char, Pos: pos}
+			}
+		case p.isZshProcessSubstitution(char, pos):
+			if !p.addSubstitution(pos, pos+2) {
+				if p.partial {
+					p.resume = pos
+
+					return nil
+				}
+
+				return &UnbalancedQuotesError{Quote: '(', Pos: pos}
+			}
+		case p.isANSICQuote(char, pos):
+			if !p.addANSICQuote(pos) {
+				if p.partial {
+					p.resume = pos
+
+					return nil
+				}
+
+				return &UnbalancedQuotesError{Quote: '\'', Pos: pos}
+			}
+		case char == '$' && p.lookup != nil && !p.inSingleQuotes:
+			if err := p.expandVariable(char, pos); err != nil {
+				return err
+			}
+		case p.isTildePrefix(char, pos):
+			p.expandTildePrefix(char, pos)
+		case p.isCommentStart(char):
+			p.skipLine(pos)
+
+			if p.keepComments {
+				p.emitToken(p.str[pos:p.skip], pos, p.skip)
+			}
+		case p.isHeredocStart(char, pos):
+			if p.partial {
+				// the body follows on the next lines, so the rest is parsed once the input is complete
+				p.resume = pos
+
+				return nil
+			}
+
+			if err := p.addHeredocStart(pos); err != nil {
+				return err
+			}
+		case redirection != "":
+			p.addRedirection(redirection, redirectionStart)
+		case operator != "":
+			p.flushToken(pos)
+			p.emitToken(operator, pos, pos+len(operator))
+			p.skip = pos + len(operator)
+		case p.isDoubledQuote(char, pos):
+			p.addToken(char, pos)
+			if p.keepQuote {
+				p.addToken(char, pos+1)
+			}
+
+			p.skip = pos + 2
+		case p.isLineContinuation(char, pos):
+			p.skip = pos + 2
+		case p.fish && char == '\\' && p.inSingleQuotes:
+			// fish only allows escaped single quotes and backslashes in single quotes
+			switch p.nextRune(pos) {
+			case '\'', '\\':
+				p.markToken(pos)
+				p.escaped = true
+				p.escapeStart = pos
+			default:
+				p.addToken(char, pos)
+			}
+		case p.isRedundantEscape(char, pos):
+			// keep the escape character literally, it does not escape anything special
+			p.addEscaped(char, pos)
+		case char == p.escapeChar && p.escapeChar != 0 && (!p.cmd || !p.inDoubleQuotes):
+			p.markToken(pos)
+
+			if !p.ignBackslashes && (!p.inSingleQuotes || p.escapeSingle) {
+				p.escaped = true
+				p.escapeStart = pos
+			}
+
+			switch {
+			case p.keepBackSlash, p.inSingleQuotes && !p.escapeSingle:
+				// backslashes are kept in single quotes
+				p.addToken(char, pos)
+			case p.inDoubleQuotes && p.escapeChar == '\\':
+				// or in double quotes except...
+				switch p.nextRune(pos) {
+				// next character is a double quote again
+				case '"':
+				// or the escape character
+				case p.escapeChar:
+				// or an escaped variable when expanding variables or in strict POSIX mode
+				case '$':
+					if p.lookup == nil && !p.strictPOSIX && !p.fish {
+						p.addToken(char, pos)
+					}
+				// or an escaped backtick in strict POSIX mode
+				case '`':
+					if !p.strictPOSIX {
+						p.addToken(char, pos)
+					}
+				// or a decoded escape sequence
+				case 'n', 't', 'r', 'f', 'v', '0':
+					if !p.decodeEscapes {
+						p.addToken(char, pos)
+					}
+				default:
+					p.addToken(char, pos)
+				}
+			}
+
+		case char == '"' && p.isQuote(char):
+			if err := p.checkMidWordQuote(pos, p.inDoubleQuotes); err != nil {
+				return err
+			}
+
+			if err := p.checkStrictQuote(char, pos, p.inDoubleQuotes); err != nil {
+				return err
+			}
+
+			p.markToken(pos)
+			p.hasToken = true
+
+			if !p.inSingleQuotes {
+				p.inDoubleQuotes = !p.inDoubleQuotes
+				if p.inDoubleQuotes {
+					p.quoteChar = char
+					p.quoteStart = pos
+				} else {
+					p.addSpan(SpanDouble, p.quoteStart, pos+1)
+				}
+
+				if p.keepQuote {
+					p.addToken(char, pos)
+				}
+			} else {
+				p.addToken(char, pos)
+			}
+		case char != '"' && p.isQuote(char):
+			closing := p.inSingleQuotes && char == p.quoteChar
+			if err := p.checkMidWordQuote(pos, closing); err != nil {
+				return err
+			}
+
+			if err := p.checkStrictQuote(char, pos, closing); err != nil {
+				return err
+			}
+
+			p.markToken(pos)
+			p.hasToken = true
+
+			switch {
+			case p.inDoubleQuotes, p.inSingleQuotes && !closing:
+				// other quotes are literal inside quotes
+				p.addToken(char, pos)
+			default:
+				p.inSingleQuotes = !closing
+				if closing {
+					p.addSpan(SpanSingle, p.quoteStart, pos+1)
+				} else {
+					p.quoteChar = char
+					p.quoteStart = pos
+				}
+
+				if p.keepQuote {
+					p.addToken(char, pos)
+				}
+			}
+		case p.isCRLF(char, pos):
+			p.separate("\n", pos, pos+2)
+		case p.isDelimiter(pos):
+			p.separate(p.delim, pos, pos+len(p.delim))
+		case p.isSeparator(char):
+			if !p.inSingleQuotes && !p.inDoubleQuotes {
+				p.emitEmptyField(pos)
+			}
+
+			end := p.runeEnd(pos)
+
+			switch {
+			case p.inSingleQuotes, p.inDoubleQuotes:
+				p.addToken(char, pos)
+			case p.keepSep && p.foldSep:
+				p.flushToken(pos)
+
+				end = p.separatorRunEnd(pos)
+				if p.tabsAsSpaces {
+					p.emitToken(strings.ReplaceAll(p.str[pos:end], "\t", " "), pos, end)
+				} else {
+					p.emitToken(p.str[pos:end], pos, end)
+				}
+				p.skip = end
+			case p.keepSep:
+				p.flushToken(pos)
+				p.emitToken(string(char), pos, end)
+			default:
+				p.flushToken(pos)
+			}
+
+			if p.str[end-1] == '\n' && len(p.heredocs) > 0 && !p.inSingleQuotes && !p.inDoubleQuotes {
+				if err := p.addHeredocBodies(end); err != nil {
+					return err
+				}
+			}
+		case p.isStrayQuote(char):
+			return &UnexpectedQuoteError{Quote: char, Pos: pos}
+		case p.isPadding(char):
+			p.addPadding(char)
+		default:
+			p.addToken(char, pos)
+		}
+	}
+
+	p.resume = len(str)
+
+	return nil
+}
+
+// continueOnError returns true if the tokens parsed so far should be returned along with err.
+func (p *parseState) continueOnError(err error) bool {
+	var shellErr *ShellCharactersFoundError
+	var limitErr *TooManyTokensError
+
+	return (p.contShell && errors.As(err, &shellErr)) || errors.As(err, &limitErr)
+}
+
+// checkStrictQuote returns UnexpectedQuoteError if an opening quote directly follows
+// a closing quote of the same kind. Closing quotes are remembered to verify the next quote.
+func (p *parseState) checkStrictQuote(char rune, pos int, closing bool) error {
+	switch {
+	case !p.strictQuotes, p.inSingleQuotes && !closing, p.inDoubleQuotes && !closing:
+		return nil
+	case closing:
+		p.quoteClosed = p.runeEnd(pos)
+	case p.quoteClosed == pos && p.quoteChar == char:
+		return &UnexpectedQuoteError{Quote: char, Pos: pos}
+	}
+
+	return nil
+}
+
+// isStrayQuote returns true if char is an unquoted typographic quote rejected by SplitStrictQuotes.
+func (p *parseState) isStrayQuote(char rune) bool {
+	return p.strictQuotes && !p.inSingleQuotes && !p.inDoubleQuotes && strings.ContainsRune(typographicQuotes, char)
+}
+
+// checkMidWordQuote returns MidWordQuoteError if an opening quote is attached to the
+// current token. Closing quotes are remembered to verify the following character.
+func (p *parseState) checkMidWordQuote(pos int, closing bool) error {
+	switch {
+	case !p.rejectMidQuote, p.inSingleQuotes && !closing, p.inDoubleQuotes && !closing:
+		return nil
+	case closing:
+		p.quoteEnd = pos
+	case p.tokenStart != -1:
+		return &MidWordQuoteError{Pos: pos}
+	}
+
+	return nil
+}
+
+// matchOperator returns the longest operator starting at pos outside of quotes.
+func (p *parseState) matchOperator(pos int) string {
+	if len(p.operators) == 0 || p.escaped || p.inSingleQuotes || p.inDoubleQuotes {
+		return ""
+	}
+
+	for _, op := range p.operators {
+		if strings.HasPrefix(p.str[pos:], op) {
+			return op
+		}
+	}
+
+	return ""
+}
+
+// isCommentStart returns true if char starts a comment.
+func (p *parseState) isCommentStart(char rune) bool {
+	return p.stripComments && char == p.commentChar && p.tokenStart == -1 && !p.inSingleQuotes && !p.inDoubleQuotes
+}
+
+// skipLine skips all characters from pos until the end of the line.
+func (p *parseState) skipLine(pos int) {
+	end := strings.IndexByte(p.str[pos:], '\n')
+	if end == -1 {
+		p.skip = len(p.str)
+
+		return
+	}
+
+	p.skip = pos + end
+}
+
+// isRawStart returns true if a raw region starts at pos.
+func (p *parseState) isRawStart(str string, pos int) bool {
+	if p.rawStart == "" || p.inSingleQuotes || p.inDoubleQuotes {
+		return false
+	}
+
+	return strings.HasPrefix(str[pos:], p.rawStart)
+}
+
+// addRawRegion adds the raw region starting at pos verbatim to the current token.
+// Returns false if the region is not terminated.
+func (p *parseState) addRawRegion(str string, pos int) bool {
+	start := pos + len(p.rawStart)
+
+	length := strings.Index(str[start:], p.rawEnd)
+	if length == -1 {
+		return false
+	}
+
+	p.markToken(pos)
+	p.hasToken = true
+
+	end := start + length + len(p.rawEnd)
+	if p.keepQuote {
+		p.token.WriteString(str[pos:end])
+	} else {
+		p.token.WriteString(str[start : start+length])
+	}
+
+	p.skip = end
+
+	return true
+}
+
+// isDoubledQuote returns true if the quote at pos is followed by the same quote within a quoted section.
+func (p *parseState) isDoubledQuote(char rune, pos int) bool {
+	switch {
+	case !p.doubledQuotes:
+		return false
+	case char == '"' && p.inDoubleQuotes, char == p.quoteChar && p.inSingleQuotes:
+		return p.nextRune(pos) == char
+	}
+
+	return false
+}
+
+// runeEnd returns the position after the rune at pos. Invalid utf-8 bytes
+// are decoded as utf8.RuneError but only span a single byte of the input.
+func (p *parseState) runeEnd(pos int) int {
+	_, size := utf8.DecodeRuneInString(p.str[pos:])
+
+	return pos + size
+}
+
+// nextRune returns the rune following the one at pos or 0 if it is the last rune.
+func (p *parseState) nextRune(pos int) rune {
+	next := p.runeEnd(pos)
+	if next >= len(p.str) {
+		return 0
+	}
+
+	nextChar, _ := utf8.DecodeRuneInString(p.str[next:])
+
+	return nextChar
+}
+
+// markToken remembers the start position of the current token.
+func (p *parseState) markToken(pos int) {
+	if p.tokenStart == -1 {
+		p.tokenStart = pos
+	}
+}
+
+// emitToken passes the token to the emit callback unless parsing has been stopped.
+func (p *parseState) emitToken(token string, start, end int) {
+	if p.stopped {
+		return
+	}
+
+	if p.rejectNul && strings.IndexByte(token, 0) != -1 {
+		p.stopped = true
+		p.limitErr = &InvalidCharacterError{Char: 0, Pos: start}
+
+		return
+	}
+
+	if p.maxTokens > 0 && p.numTokens >= p.maxTokens {
+		p.stopped = true
+		p.limitErr = &TooManyTokensError{Max: p.maxTokens, Pos: start}
+
+		return
+	}
+
+	if p.checkEnv && p.envPhase {
+		if err := p.checkEnvValue(token, start, end); err != nil {
+			p.stopped = true
+			p.limitErr = err
+
+			return
+		}
+	}
+
+	p.numTokens++
+	p.lastField = false
+
+	if !p.emit(token, start, end) {
+		p.stopped = true
+	}
+}
+
+// flushToken emits the current token (if any) which ends at position end.
+func (p *parseState) flushToken(end int) {
+	if p.hasToken {
+		if p.padding > 0 {
+			p.token.Truncate(p.token.Len() - p.padding)
+			p.padding = 0
+		}
+
+		// tokens without quotes or escapes are taken from the input without allocation
+		token := p.str[p.tokenStart:end]
+		switch {
+		case string(p.token.Bytes()) == token:
+		case p.borrowTokens:
+			token = unsafe.String(unsafe.SliceData(p.token.Bytes()), p.token.Len())
+		default:
+			token = p.token.String()
+		}
+
+		p.emitToken(token, p.tokenStart, end)
+		p.lastField = true
+		p.token.Reset()
+
+		p.hasToken = false
+	}
+
+	p.tokenStart = -1
+}
+
+// addEscaped adds an escaped character to the current token, escaped characters are no shell characters.
+func (p *parseState) addEscaped(char rune, pos int) {
+	p.markToken(pos)
+	p.hasToken = true
+	p.padding = 0
+	p.token.WriteRune(char)
+}
+
+func (p *parseState) addToken(char rune, pos int) {
+	first := !p.hasToken
+
+	p.markToken(pos)
+	p.hasToken = true
+	p.padding = 0
+
+	// exit early if we do not search for shell characters (anymore)
+	switch {
+	case p.ignShell, p.inSingleQuotes, p.firstShellPos != -1:
+		p.token.WriteRune(char)
+
+		return
+	}
+
+	switch {
+	case p.isLiteralShellChar(char, pos, first):
+		// no special meaning at this position
+	case p.inDoubleQuotes:
+		if strings.ContainsRune(p.doubleShell, char) {
+			p.firstShellPos = pos
+		}
+	case strings.ContainsRune(p.outsideShell, char):
+		p.firstShellPos = pos
+	case char == p.escapeChar:
+		if !p.keepBackSlash && !p.ignBackslashes {
+			p.firstShellPos = pos
+		}
+	}
+
+	if p.firstShellPos == pos {
+		p.firstShellCat = p.shellCategory(char, pos)
+		p.firstShellChar = char
+	}
+
+	p.token.WriteRune(char)
+}
+
+// shellCategory returns the category of the shell character at pos.
+func (p *parseState) shellCategory(char rune, pos int) ShellCharacterCategory {
+	switch char {
+	case '`':
+		return ShellCharacterCommandSubstitution
+	case '$':
+		if pos+1 >= len(p.str) {
+			return ShellCharacterOther
+		}
+
+		next := p.str[pos+1]
+		switch {
+		case next == '(':
+			return ShellCharacterCommandSubstitution
+		case next == '{', next == '_', next >= 'a' && next <= 'z', next >= 'A' && next <= 'Z', next >= '0' && next <= '9':
+			return ShellCharacterVariableExpansion
+		}
+	}
+
+	return ShellCharacterOther
+}
+
+// isLiteralShellChar returns true if char has no special meaning at pos although it
+// is a shell character: a "$" which does not start an expansion, ex.: "cost$" or "a $ b",
+// a "~" which neither starts a word nor follows a "=" or ":" and a "!" unless
+// SplitFlagHistoryExpansion is set.
+func (p *parseState) isLiteralShellChar(char rune, pos int, first bool) bool {
+	switch char {
+	case '$':
+		next := p.nextRune(pos)
+		switch {
+		case next == 0:
+			return true
+		case next == '\'', next == '"':
+			// ANSI-C and locale quoting only exist outside of double quotes
+			return p.inDoubleQuotes
+		case next == p.escapeChar, strings.ContainsRune("({[_$?!#@*-", next):
+			return false
+		case next >= 'a' && next <= 'z', next >= 'A' && next <= 'Z', next >= '0' && next <= '9':
+			return false
+		}
+
+		return true
+	case '~':
+		if first || pos == 0 {
+			return false
+		}
+
+		prev := p.str[pos-1]
+
+		return prev != '=' && prev != ':'
+	case '!':
+		return !p.flagHistory
+	}
+
+	return false
+}
+
+// shellError returns the ShellCharactersFoundError for the first shell character found.
+func (p *parseState) shellError() error {
+	line, col := linePosition(p.str, p.firstShellPos)
+
+	return &ShellCharactersFoundError{
+		pos:      p.firstShellPos,
+		runePos:  utf8.RuneCountInString(p.str[:p.firstShellPos]),
+		Line:     line,
+		Col:      col,
+		Char:     p.firstShellChar,
+		Category: p.firstShellCat,
+	}
+}
+
+// linePosition returns the 1-based line and rune column of pos in str.
+func linePosition(str string, pos int) (line, col int) {
+	str = str[:pos]
+	line = 1 + strings.Count(str, "\n")
+	col = 1 + utf8.RuneCountInString(str[strings.LastIndexByte(str, '\n')+1:])
+
+	return line, col
 }
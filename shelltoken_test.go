@@ -1,6 +1,7 @@
 package shelltoken_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/sni/shelltoken"
@@ -77,8 +78,10 @@ func TestSplitLinuxErrors(t *testing.T) {
 		in  string
 		err string
 	}{
-		{"test 'arg1 arg2", "unbalanced quotes"},
-		{`test "arg1 arg2`, "unbalanced quotes"},
+		{"test 'arg1 arg2", "unbalanced ' quote opened at position 5"},
+		{`test "arg1 arg2`, `unbalanced " quote opened at position 5`},
+		{`test "arg1 'arg2`, `unbalanced " quote opened at position 5`},
+		{`test "arg1" 'arg2`, "unbalanced ' quote opened at position 12"},
 	}
 
 	for _, tst := range tests {
@@ -88,6 +91,12 @@ func TestSplitLinuxErrors(t *testing.T) {
 		assert.Nil(t, argv, "argv is nil")
 		assert.Nil(t, env, "argv is nil")
 	}
+
+	_, err := shelltoken.SplitQuotes("echo 'a", shelltoken.Whitespace)
+	quoteErr := &shelltoken.UnbalancedQuotesError{}
+	require.ErrorAs(t, err, &quoteErr)
+	assert.Equal(t, '\'', quoteErr.Quote)
+	assert.Equal(t, 5, quoteErr.Pos)
 }
 
 func TestSplitLinuxShellCharacters(t *testing.T) {
@@ -107,6 +116,10 @@ func TestSplitLinuxShellCharacters(t *testing.T) {
 		{"ls *.txt", true},
 		{"./test arg1 -P 'm1|m2';", true},
 		{"ENV='test' test 2>&1", true},
+		{`test a\|b`, true},
+		{`test \$HOME "\$HOME"`, true},
+		{`test \(`, true},
+		{`test a\\|b`, true},
 	}
 
 	shellError := &shelltoken.ShellCharactersFoundError{}
@@ -190,3 +203,818 @@ func TestSplitQuotesAny(t *testing.T) {
 		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
 	}
 }
+
+func TestSplitRejectMidWordQuotes(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{`a "b c" 'd'`, []string{"a", "b c", "d"}},
+		{`a\"b "a'b" 'a"b'`, []string{`a"b`, `a'b`, `a"b`}},
+		{`"" ''`, []string{"", ""}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitRejectMidWordQuotes)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	errTests := []struct {
+		in  string
+		pos int
+	}{
+		{`ab"cd"`, 2},
+		{`"ab"cd`, 3},
+		{`x 'a''b'`, 4},
+		{`x \a"b"`, 4},
+		{`x "a"\ b`, 4},
+	}
+
+	for _, tst := range errTests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitRejectMidWordQuotes)
+		quoteErr := &shelltoken.MidWordQuoteError{}
+		require.ErrorAsf(t, err, &quoteErr, "expected mid word quote error for: %s", tst.in)
+		assert.Equalf(t, tst.pos, quoteErr.Pos, "position for: %s", tst.in)
+		assert.Nil(t, argv, "argv is nil")
+	}
+
+	// default sh behavior concatenates
+	argv, err := shelltoken.SplitQuotes(`ab"cd"`, shelltoken.Whitespace)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abcd"}, argv)
+}
+
+func TestSplitLinuxSingleQuotedBackslash(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{`'a\' b`, []string{`a\`, `b`}},
+		{`'it'\''s'`, []string{`it's`}},
+		{`'\'\'`, []string{`\'`}},
+	}
+
+	for _, tst := range tests {
+		_, argv, err := shelltoken.SplitLinux(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+}
+
+func TestSplitLinuxShellCharacterCategory(t *testing.T) {
+	tests := []struct {
+		in       string
+		category shelltoken.ShellCharacterCategory
+	}{
+		{`echo $(id)`, shelltoken.ShellCharacterCommandSubstitution},
+		{"echo `id`", shelltoken.ShellCharacterCommandSubstitution},
+		{`echo "$(id)"`, shelltoken.ShellCharacterCommandSubstitution},
+		{`echo ${HOME}`, shelltoken.ShellCharacterVariableExpansion},
+		{`echo $HOME`, shelltoken.ShellCharacterVariableExpansion},
+		{`echo "x$_x"`, shelltoken.ShellCharacterVariableExpansion},
+		{`echo $1`, shelltoken.ShellCharacterVariableExpansion},
+		{`echo $$`, shelltoken.ShellCharacterOther},
+		{`echo $? x`, shelltoken.ShellCharacterOther},
+		{`echo a | wc`, shelltoken.ShellCharacterOther},
+		{`echo a; $(id)`, shelltoken.ShellCharacterOther},
+	}
+
+	for _, tst := range tests {
+		_, _, err := shelltoken.SplitLinux(tst.in)
+		shellErr := &shelltoken.ShellCharactersFoundError{}
+		require.ErrorAsf(t, err, &shellErr, "expected shell error for: %s", tst.in)
+		assert.Equalf(t, tst.category, shellErr.Category, "category for: %s", tst.in)
+	}
+
+	_, _, err := shelltoken.SplitLinux(`echo $(id)`)
+	assert.EqualError(t, err, "shell character '$' at line 1 column 6 (offset 5, command substitution)")
+}
+
+func TestSplitLinuxLiteralShellCharacters(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{`echo cost$`, []string{"echo", "cost$"}},
+		{`echo $ x "a $" "$"`, []string{"echo", "$", "x", "a $", "$"}},
+		{`echo a$.b a$/b`, []string{"echo", "a$.b", "a$/b"}},
+		{`echo "$'x'"`, []string{"echo", "$'x'"}},
+		{`echo a~b x~`, []string{"echo", "a~b", "x~"}},
+	}
+
+	for _, tst := range tests {
+		_, argv, err := shelltoken.SplitLinux(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	flagged := []string{
+		`echo $(x)`, `echo a$b`, `echo $1`, `echo $$`, `echo $?`, `echo ${x}`, `echo $'x'`, `echo $"x"`,
+		`echo $[1+1]`, `echo "$@"`, `echo ~`, `echo ~/x`, `echo a=~/x`, `echo PATH=a:~/bin`, `echo "a" ~`,
+	}
+
+	for _, str := range flagged {
+		_, _, err := shelltoken.SplitLinux(str)
+		require.ErrorIsf(t, err, shelltoken.ErrShellCharacters, "expected shell error for: %s", str)
+	}
+}
+
+func TestSplitFlagHistoryExpansion(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+		pos int
+	}{
+		{`a!b`, []string{"a!b"}, 1},
+		{`echo hi! ! x`, []string{"echo", "hi!", "!", "x"}, 7},
+		{`echo "a!b" 'c!'`, []string{"echo", "a!b", "c!"}, -1},
+		{`echo a\!b`, []string{"echo", "a!b"}, 7},
+	}
+
+	for _, tst := range tests {
+		_, argv, err := shelltoken.SplitLinux(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+
+		argv, err = shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters|shelltoken.SplitFlagHistoryExpansion)
+		if tst.pos == -1 {
+			require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+			assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+
+			continue
+		}
+
+		shellErr := &shelltoken.ShellCharactersFoundError{}
+		require.ErrorAsf(t, err, &shellErr, "expected shell error for: %s", tst.in)
+		assert.Equalf(t, tst.pos, shellErr.Offset(), "position for: %s", tst.in)
+		assert.Equalf(t, '!', shellErr.Char, "character for: %s", tst.in)
+	}
+}
+
+func TestSplitDoubledQuotes(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{`"a""b"`, []string{`a"b`}},
+		{`'a''b'`, []string{`a'b`}},
+		{`"" ''`, []string{``, ``}},
+		{`"""" ''''`, []string{`"`, `'`}},
+		{`"a''b" 'a""b'`, []string{`a''b`, `a""b`}},
+		{`a""b`, []string{`ab`}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitDoubledQuotes)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+}
+
+func TestSplitLinuxMultibyteBackslash(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{`"é\\"`, []string{`é\`}},
+		{`"ü\\n"`, []string{`ü\n`}},
+		{`"ü\n"`, []string{`ü\n`}},
+		{`"é\"ü" x`, []string{`é"ü`, `x`}},
+		{`"日本\語"`, []string{`日本\語`}},
+		{`"\é"`, []string{`\é`}},
+	}
+
+	for _, tst := range tests {
+		_, argv, err := shelltoken.SplitLinux(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+}
+
+func TestSplitTrailingBackslash(t *testing.T) {
+	argv, err := shelltoken.SplitQuotes(`a\`, shelltoken.Whitespace)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, argv)
+
+	argv, err = shelltoken.SplitQuotes(`"a\`, shelltoken.Whitespace)
+	require.EqualError(t, err, `unbalanced " quote opened at position 0`)
+	assert.Nil(t, argv)
+
+	argv, err = shelltoken.SplitQuotes(`"a\`, shelltoken.Whitespace, shelltoken.SplitKeepQuotes)
+	require.EqualError(t, err, `unbalanced " quote opened at position 0`)
+	assert.Nil(t, argv)
+}
+
+func TestSplitSuffixNoPanic(t *testing.T) {
+	prefixes := []string{"", "a", `"a`, `'a`, `"a\`, `a\`, `"é`, `"é\`, `'é\`}
+	chars := []string{`\`, `"`, `'`, " ", "\t", "\n", "$", "`", "|", "(", ")", "é", "日", "\x00", "\xff", "a"}
+
+	suffixes := []string{""}
+	for _, c1 := range chars {
+		suffixes = append(suffixes, c1)
+		for _, c2 := range chars {
+			suffixes = append(suffixes, c1+c2)
+		}
+	}
+
+	options := []shelltoken.SplitOption{
+		shelltoken.SplitNoOptions,
+		shelltoken.SplitKeepBackslashes | shelltoken.SplitKeepQuotes | shelltoken.SplitKeepSeparator,
+		shelltoken.SplitIgnoreBackslashes | shelltoken.SplitContinueOnShellCharacters,
+		shelltoken.SplitStopOnShellCharacters | shelltoken.SplitDoubledQuotes | shelltoken.SplitRejectMidWordQuotes,
+	}
+
+	for _, prefix := range prefixes {
+		for _, suffix := range suffixes {
+			str := prefix + suffix
+			assert.NotPanicsf(t, func() {
+				for _, opt := range options {
+					_, _ = shelltoken.SplitQuotes(str, shelltoken.Whitespace, opt)
+				}
+
+				_, _, _ = shelltoken.SplitLinux(str)
+				_, _, _ = shelltoken.SplitWindows(str)
+			}, "input: %q", str)
+		}
+	}
+}
+
+func TestSplitStripComments(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{"echo hi # there", []string{"echo", "hi"}},
+		{"echo hi #there", []string{"echo", "hi"}},
+		{"# only a comment", []string{}},
+		{"echo a#b", []string{"echo", "a#b"}},
+		{`echo "#a" '#b' \#c`, []string{"echo", "#a", "#b", "#c"}},
+		{"echo a # comment\necho b", []string{"echo", "a", "echo", "b"}},
+		{"echo a #'unbalanced\necho b", []string{"echo", "a", "echo", "b"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitStripComments)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	argv, err := shelltoken.SplitQuotes("echo hi # there", shelltoken.Whitespace)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"echo", "hi", "#", "there"}, argv)
+
+	argv, err = shelltoken.SplitQuotes("echo a\n# comment\nb", shelltoken.Whitespace, shelltoken.SplitStripComments|shelltoken.SplitKeepSeparator)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"echo", " ", "a", "\n", "\n", "b"}, argv)
+
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace, shelltoken.SplitStripComments)
+	cfg.CommentChar = ';'
+	argv, err = cfg.Split("echo a#b ; comment")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"echo", "a#b"}, argv)
+}
+
+func TestSentinelErrors(t *testing.T) {
+	_, err := shelltoken.SplitQuotes(`echo "a`, shelltoken.Whitespace)
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+	assert.NotErrorIs(t, err, shelltoken.ErrShellCharacters)
+
+	_, _, err = shelltoken.SplitLinux("echo a | b")
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+	assert.NotErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+
+	shellErr := &shelltoken.ShellCharactersFoundError{}
+	require.ErrorAs(t, err, &shellErr)
+	require.EqualError(t, shellErr, "shell character '|' at line 1 column 8 (offset 7)")
+}
+
+func TestSplitOperators(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{`a&&b|c`, []string{"a", "&&", "b", "|", "c"}},
+		{`a || b & c;d;;e`, []string{"a", "||", "b", "&", "c", ";", "d", ";;", "e"}},
+		{`(cd /tmp; ls)|wc`, []string{"(", "cd", "/tmp", ";", "ls", ")", "|", "wc"}},
+		{`echo "a&&b" 'c|d' e"(f)"`, []string{"echo", "a&&b", "c|d", "e(f)"}},
+		{`a&&&b`, []string{"a", "&&", "&", "b"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitOperators)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	argv, err := shelltoken.SplitQuotes(`a|b>c`, shelltoken.Whitespace, shelltoken.SplitOperators|shelltoken.SplitStopOnShellCharacters)
+	require.Error(t, err)
+	assert.Nil(t, argv)
+}
+
+func TestSplitKeepEmptyFields(t *testing.T) {
+	tests := []struct {
+		in      string
+		options shelltoken.SplitOption
+		res     []string
+	}{
+		{`a,,b`, 0, []string{"a", "", "b"}},
+		{`,a,`, 0, []string{"", "a", ""}},
+		{`,`, 0, []string{"", ""}},
+		{``, 0, []string{}},
+		{`a,"",b`, 0, []string{"a", "", "b"}},
+		{`"a,,b",,'c,'`, 0, []string{"a,,b", "", "c,"}},
+		{`a\,,b\,`, 0, []string{"a,", "b,"}},
+		{`a,,b`, shelltoken.SplitKeepSeparator, []string{"a", ",", "", ",", "b"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, ",", tst.options|shelltoken.SplitKeepEmptyFields)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	// default behavior drops empty fields
+	argv, err := shelltoken.SplitQuotes(`,a,,b,`, ",")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, argv)
+}
+
+func TestSplitDecodeEscapes(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{`"a\nb"`, []string{"a\nb"}},
+		{`"a\tb"`, []string{"a\tb"}},
+		{`"a\rb"`, []string{"a\rb"}},
+		{`"a\fb"`, []string{"a\fb"}},
+		{`"a\vb"`, []string{"a\vb"}},
+		{`"a\0b"`, []string{"a\x00b"}},
+		{`"a\\nb"`, []string{`a\nb`}},
+		{`"a\"b\xc"`, []string{`a"b\xc`}},
+		{`'a\nb' a\nb`, []string{`a\nb`, "anb"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitDecodeEscapes)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	// escapes are kept without the option
+	argv, err := shelltoken.SplitQuotes(`"a\nb"`, shelltoken.Whitespace)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`a\nb`}, argv)
+}
+
+func TestSplitLineContinuation(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{"echo a\\\nb", []string{"echo", "ab"}},
+		{"echo a \\\n  b", []string{"echo", "a", "b"}},
+		{"echo \"a\\\nb\"", []string{"echo", "ab"}},
+		{"echo 'a\\\nb'", []string{"echo", "a\\\nb"}},
+		{"echo a\\\\\nb", []string{"echo", "a\\", "b"}},
+		{"echo a\\", []string{"echo", "a"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitLineContinuation)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	// without the option the newline is escaped
+	argv, err := shelltoken.SplitQuotes("echo a\\\nb", shelltoken.Whitespace)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"echo", "a\nb"}, argv)
+}
+
+func TestSplitStrictPOSIX(t *testing.T) {
+	// expected results taken from dash: eval "set -- $in"
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{`"a\b"`, []string{`a\b`}},
+		{`"a\$b"`, []string{`a$b`}},
+		{"\"a\\`b\"", []string{"a`b"}},
+		{`"a\"b"`, []string{`a"b`}},
+		{`"a\\b"`, []string{`a\b`}},
+		{`"a\nb"`, []string{`a\nb`}},
+		{`"a\'b"`, []string{`a\'b`}},
+		{"\"a\\\nb\"", []string{"ab"}},
+		{`'a\b'`, []string{`a\b`}},
+		{`a\b`, []string{`ab`}},
+		{`a\$b`, []string{`a$b`}},
+		{"a\\\nb", []string{"ab"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitStrictPOSIX)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+}
+
+func TestShellCharactersFoundErrorPosition(t *testing.T) {
+	tests := []struct {
+		in         string
+		line       int
+		col        int
+		offset     int
+		runeOffset int
+		char       rune
+	}{
+		{"a|b", 1, 2, 1, 1, '|'},
+		{"echo a\necho b; c", 2, 7, 13, 13, ';'},
+		{"echo 'x\ny' ö\nü >x", 3, 3, 17, 15, '>'},
+		{"\n\n|", 3, 1, 2, 2, '|'},
+		{`echo "a $b"`, 1, 9, 8, 8, '$'},
+		{"echo \"a `b`\"", 1, 9, 8, 8, '`'},
+		{"日本語|", 1, 4, 9, 3, '|'},
+		{`"日本語$x"`, 1, 5, 10, 4, '$'},
+	}
+
+	for _, tst := range tests {
+		_, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters)
+		shellErr := &shelltoken.ShellCharactersFoundError{}
+		require.ErrorAsf(t, err, &shellErr, "expected shell error for: %s", tst.in)
+
+		line, col := shellErr.Position()
+		assert.Equalf(t, tst.line, line, "line of: %s", tst.in)
+		assert.Equalf(t, tst.col, col, "column of: %s", tst.in)
+		assert.Equalf(t, tst.offset, shellErr.Offset(), "offset of: %s", tst.in)
+		assert.Equalf(t, tst.runeOffset, shellErr.RuneOffset(), "rune offset of: %s", tst.in)
+		assert.Equalf(t, tst.char, shellErr.Char, "character of: %s", tst.in)
+	}
+}
+
+func TestSplitNoQuotes(t *testing.T) {
+	tests := []struct {
+		in      string
+		options shelltoken.SplitOption
+		res     []string
+	}{
+		{`it's here`, shelltoken.SplitNoSingleQuotes, []string{"it's", "here"}},
+		{`"it's here" 'a b'`, shelltoken.SplitNoSingleQuotes, []string{"it's here", "'a", "b'"}},
+		{`say "hi there`, shelltoken.SplitNoDoubleQuotes, []string{"say", `"hi`, "there"}},
+		{`'say "hi"' "a b"`, shelltoken.SplitNoDoubleQuotes, []string{`say "hi"`, `"a`, `b"`}},
+		{`'a b' "c d"`, shelltoken.SplitNoSingleQuotes | shelltoken.SplitNoDoubleQuotes, []string{"'a", "b'", `"c`, `d"`}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, tst.options)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	_, err := shelltoken.SplitQuotes(`it's | here`, shelltoken.Whitespace, shelltoken.SplitNoSingleQuotes|shelltoken.SplitStopOnShellCharacters)
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+
+	_, err = shelltoken.SplitQuotes(`'$HOME'`, shelltoken.Whitespace, shelltoken.SplitNoSingleQuotes|shelltoken.SplitStopOnShellCharacters)
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+}
+
+func TestSplitEscapeInSingleQuotes(t *testing.T) {
+	tests := []struct {
+		in     string
+		posix  []string
+		escape []string
+	}{
+		{`'it\'s'`, nil, []string{"it's"}},
+		{`'a\\b' c`, []string{`a\\b`, "c"}, []string{`a\b`, "c"}},
+		{`'a\b'`, []string{`a\b`}, []string{"ab"}},
+		{`'it'\''s'`, []string{"it's"}, []string{"it's"}},
+		{`"a\'b"`, []string{`a\'b`}, []string{`a\'b`}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace)
+		if tst.posix == nil {
+			require.Errorf(t, err, "expected error for: %s", tst.in)
+		} else {
+			require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+			assert.Equalf(t, tst.posix, argv, "Tokenize: %v -> %v", tst.in, argv)
+		}
+
+		argv, err = shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitEscapeInSingleQuotes)
+		if tst.escape == nil {
+			require.Errorf(t, err, "expected error for: %s", tst.in)
+		} else {
+			require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+			assert.Equalf(t, tst.escape, argv, "Tokenize: %v -> %v", tst.in, argv)
+		}
+	}
+}
+
+func TestSplitNormalizeNewlines(t *testing.T) {
+	tests := []struct {
+		in         string
+		plain      []string
+		normalized []string
+	}{
+		{"a\r\nb", []string{"a", "\r", "\n", "b"}, []string{"a", "\n", "b"}},
+		{"a \r\n\r\nb", []string{"a", " ", "\r", "\n", "\r", "\n", "b"}, []string{"a", " ", "\n", "\n", "b"}},
+		{"'a\r\nb'\r\n", []string{"a\r\nb", "\r", "\n"}, []string{"a\r\nb", "\n"}},
+		{"\"a\r\n\"\r\nb", []string{"a\r\n", "\r", "\n", "b"}, []string{"a\r\n", "\n", "b"}},
+		{"a\rb", []string{"a", "\r", "b"}, []string{"a", "\r", "b"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitKeepSeparator)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.plain, argv, "Tokenize: %q -> %q", tst.in, argv)
+
+		argv, err = shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitKeepSeparator|shelltoken.SplitNormalizeNewlines)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.normalized, argv, "Tokenize: %q -> %q", tst.in, argv)
+	}
+
+	argv, err := shelltoken.SplitQuotes("a\r\n\r\nb", shelltoken.Whitespace, shelltoken.SplitKeepEmptyFields|shelltoken.SplitNormalizeNewlines)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "", "b"}, argv)
+}
+
+func TestSplitFoldSeparators(t *testing.T) {
+	tests := []struct {
+		in      string
+		options shelltoken.SplitOption
+		res     []string
+	}{
+		{"a   b", shelltoken.SplitNoOptions, []string{"a", "   ", "b"}},
+		{"a \t \tb\t", shelltoken.SplitNoOptions, []string{"a", " \t \t", "b", "\t"}},
+		{" \ta 'b  c'\t\t d", shelltoken.SplitNoOptions, []string{" \t", "a", " ", "b  c", "\t\t ", "d"}},
+		{`a\  \ b`, shelltoken.SplitNoOptions, []string{"a ", " ", " b"}},
+		{"  a  ", shelltoken.SplitKeepEmptyFields, []string{"", "  ", "a", "  ", ""}},
+		{"a   b", shelltoken.SplitKeepQuotes, []string{"a", "   ", "b"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitKeepSeparator|shelltoken.SplitFoldSeparators|tst.options)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %q -> %q", tst.in, argv)
+	}
+
+	// without SplitKeepSeparator separators are dropped anyway
+	argv, err := shelltoken.SplitQuotes("a \t b", shelltoken.Whitespace, shelltoken.SplitFoldSeparators)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, argv)
+
+	argv, err = shelltoken.SplitQuotes("a \t b", shelltoken.Whitespace, shelltoken.SplitKeepSeparator)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", " ", "\t", " ", "b"}, argv)
+}
+
+func TestSplitKeepLeadingSeparators(t *testing.T) {
+	tests := []struct {
+		in     string
+		res    []string
+		folded []string
+	}{
+		{"  a", []string{" ", " ", "a"}, []string{"  ", "a"}},
+		{"\ta", []string{"\t", "a"}, []string{"\t", "a"}},
+		{" \t 'a b'  ", []string{" ", "\t", " ", "'a b'", " ", " "}, []string{" \t ", "'a b'", "  "}},
+		{"   ", []string{" ", " ", " "}, []string{"   "}},
+	}
+
+	for _, tst := range tests {
+		options := shelltoken.SplitKeepSeparator | shelltoken.SplitKeepQuotes | shelltoken.SplitKeepBackslashes
+
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, options)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %q -> %q", tst.in, argv)
+		assert.Equalf(t, tst.in, strings.Join(argv, ""), "Reconstruct: %q", tst.in)
+
+		argv, err = shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, options|shelltoken.SplitFoldSeparators)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.folded, argv, "Tokenize folded: %q -> %q", tst.in, argv)
+		assert.Equalf(t, tst.in, strings.Join(argv, ""), "Reconstruct folded: %q", tst.in)
+	}
+}
+
+func TestSplitTabsAsSpaces(t *testing.T) {
+	tests := []struct {
+		tabs   string
+		spaces string
+	}{
+		{"a\tb", "a b"},
+		{"\ta\t\tb\t", " a  b "},
+		{"ls\t-l\t'a\tb'\t\"c\td\"", "ls -l 'a\tb' \"c\td\""},
+	}
+
+	for _, tst := range tests {
+		expect, err := shelltoken.SplitQuotes(tst.spaces, shelltoken.Whitespace, shelltoken.SplitKeepSeparator)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.spaces)
+
+		argv, err := shelltoken.SplitQuotes(tst.tabs, shelltoken.Whitespace, shelltoken.SplitKeepSeparator|shelltoken.SplitTabsAsSpaces)
+		require.NoErrorf(t, err, "error while parsing: %q", tst.tabs)
+		assert.Equalf(t, expect, argv, "Tokenize: %q -> %q", tst.tabs, argv)
+	}
+
+	argv, err := shelltoken.SplitQuotes("a\t \tb\\\tc", shelltoken.Whitespace, shelltoken.SplitKeepSeparator|shelltoken.SplitFoldSeparators|shelltoken.SplitTabsAsSpaces)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "   ", "b\tc"}, argv)
+
+	// tabs become spaces even if only spaces are separators
+	argv, err = shelltoken.SplitQuotes("a\tb", " ", shelltoken.SplitTabsAsSpaces)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, argv)
+}
+
+func TestSplitRejectNul(t *testing.T) {
+	tests := []struct {
+		in  string
+		pos int
+	}{
+		{"a\x00b", 1},
+		{"echo 'a\x00b'", 7},
+		{"\x00", 0},
+		{`echo "a\0b"`, 5},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitRejectNul|shelltoken.SplitDecodeEscapes)
+		charErr := &shelltoken.InvalidCharacterError{}
+		require.ErrorAsf(t, err, &charErr, "expected invalid character error for: %q", tst.in)
+		assert.Equalf(t, rune(0), charErr.Char, "character of: %q", tst.in)
+		assert.Equalf(t, tst.pos, charErr.Pos, "position of: %q", tst.in)
+		assert.Nil(t, argv)
+	}
+
+	_, err := shelltoken.SplitQuotes("a\x00b", shelltoken.Whitespace, shelltoken.SplitRejectNul)
+	require.EqualError(t, err, `invalid character '\x00' at position 1`)
+
+	// NUL characters are kept by default
+	argv, err := shelltoken.SplitQuotes("a\x00b c", shelltoken.Whitespace)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a\x00b", "c"}, argv)
+
+	argv, err = shelltoken.SplitQuotes(`echo "a\0b"`, shelltoken.Whitespace, shelltoken.SplitRejectNul)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"echo", `a\0b`}, argv)
+}
+
+func TestSplitStrictQuotes(t *testing.T) {
+	tests := []struct {
+		in    string
+		quote rune
+		pos   int
+	}{
+		{`"a""b"`, '"', 3},
+		{`echo 'a''b'`, '\'', 8},
+		{`x "" ""`, 0, 0},
+		{`echo “hello”`, '“', 5},
+		{`echo ‘a’`, '‘', 5},
+		{`a\`, '\\', 1},
+		{`a \\\`, '\\', 4},
+		{`"a"'b' 'c'"d"`, 0, 0},
+		{`a"b"c "“x”" '‘y’' \“z`, 0, 0},
+		{`"a\"" "a\\"`, 0, 0},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitStrictQuotes)
+		if tst.quote == 0 {
+			require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+
+			expect, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace)
+			require.NoError(t, err)
+			assert.Equalf(t, expect, argv, "Tokenize: %s -> %q", tst.in, argv)
+
+			continue
+		}
+
+		quoteErr := &shelltoken.UnexpectedQuoteError{}
+		require.ErrorAsf(t, err, &quoteErr, "expected unexpected quote error for: %s", tst.in)
+		assert.Equalf(t, tst.quote, quoteErr.Quote, "quote of: %s", tst.in)
+		assert.Equalf(t, tst.pos, quoteErr.Pos, "position of: %s", tst.in)
+		assert.Nil(t, argv)
+	}
+
+	_, err := shelltoken.SplitQuotes(`"a""b"`, shelltoken.Whitespace, shelltoken.SplitStrictQuotes)
+	require.EqualError(t, err, `unexpected '"' at position 3`)
+
+	// unbalanced quotes are reported first
+	_, err = shelltoken.SplitQuotes(`"a\`, shelltoken.Whitespace, shelltoken.SplitStrictQuotes)
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+
+	// doubled quotes are literal quotes with SplitDoubledQuotes
+	argv, err := shelltoken.SplitQuotes(`"a""b"`, shelltoken.Whitespace, shelltoken.SplitStrictQuotes|shelltoken.SplitDoubledQuotes)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`a"b`}, argv)
+
+	// configured typographic quotes are regular quotes
+	cfg := shelltoken.NewConfig(shelltoken.Whitespace, shelltoken.SplitStrictQuotes).WithQuoteSet("'\"“")
+	argv, err = cfg.Split(`echo “hello world“`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"echo", "hello world"}, argv)
+}
+
+func TestSplitKeepRedundantBackslashes(t *testing.T) {
+	tests := []struct {
+		in        string
+		redundant []string
+		escaped   []string
+	}{
+		{`a\b`, []string{`a\b`}, []string{"ab"}},
+		{`a\ b`, []string{"a b"}, []string{"a b"}},
+		{`C:\dir\file.txt`, []string{`C:\dir\file.txt`}, []string{"C:dirfile.txt"}},
+		{`a\"b \'c\'`, []string{`a"b`, "'c'"}, []string{`a"b`, "'c'"}},
+		{`a\\b \\\n`, []string{`a\b`, `\\n`}, []string{`a\b`, `\n`}},
+		{`\$HOME \|`, []string{"$HOME", "|"}, []string{"$HOME", "|"}},
+		{`\#x \ä`, []string{"#x", `\ä`}, []string{"#x", "ä"}},
+		{`"a\b" 'c\d'`, []string{`a\b`, `c\d`}, []string{`a\b`, `c\d`}},
+		{`a\`, []string{`a\`}, []string{"a"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitKeepRedundantBackslashes)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.redundant, argv, "Tokenize: %s -> %q", tst.in, argv)
+
+		argv, err = shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.escaped, argv, "Tokenize: %s -> %q", tst.in, argv)
+	}
+
+	// kept backslashes are no shell characters
+	argv, err := shelltoken.SplitQuotes(`ls C:\dir`, shelltoken.Whitespace, shelltoken.SplitKeepRedundantBackslashes|shelltoken.SplitStopOnShellCharacters)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls", `C:\dir`}, argv)
+}
+
+func TestSplitTrimTokens(t *testing.T) {
+	tests := []struct {
+		in      string
+		options shelltoken.SplitOption
+		res     []string
+	}{
+		{`a , b`, shelltoken.SplitNoOptions, []string{"a", "b"}},
+		{`a , "b "`, shelltoken.SplitNoOptions, []string{"a", "b "}},
+		{`  " a" ,' b ' , c d  `, shelltoken.SplitNoOptions, []string{" a", " b ", "c d"}},
+		{`a\  , \ b`, shelltoken.SplitNoOptions, []string{"a ", " b"}},
+		{"a\t, b\n", shelltoken.SplitNoOptions, []string{"a", "b"}},
+		{`a, ,b`, shelltoken.SplitNoOptions, []string{"a", "b"}},
+		{`a, ,b`, shelltoken.SplitKeepEmptyFields, []string{"a", "", "b"}},
+		{`a , b`, shelltoken.SplitKeepSeparator, []string{"a", ",", "b"}},
+		{`"a" "b" , c`, shelltoken.SplitKeepQuotes, []string{`"a" "b"`, "c"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, ",", shelltoken.SplitTrimTokens|tst.options)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %q -> %q", tst.in, argv)
+	}
+
+	// whitespace is kept without SplitTrimTokens
+	argv, err := shelltoken.SplitQuotes(`a , "b "`, ",")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a ", ` b `}, argv)
+}
+
+func TestSplitSmartQuotes(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{"“a b”", []string{"a b"}},
+		{"”a b“", []string{"a b"}},
+		{"‘a b’", []string{"a b"}},
+		{"’a b‘", []string{"a b"}},
+		{"echo “it’s” ‘say “hi”’", []string{"echo", "it’s", "say “hi”"}},
+		{`echo "a “b” c" 'd ‘e’ f'`, []string{"echo", "a “b” c", "d ‘e’ f"}},
+		{`echo \“a b\”`, []string{"echo", "“a", "b”"}},
+		{"“$HOME” ‘$HOME’", []string{"$HOME", "$HOME"}},
+		{`"e ” f" ‘g “h” i’`, []string{"e ” f", "g “h” i"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitSmartQuotes)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %s -> %q", tst.in, argv)
+	}
+
+	// typographic quotes are regular characters by default
+	argv, err := shelltoken.SplitQuotes("“a b”", shelltoken.Whitespace)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"“a", "b”"}, argv)
+
+	_, err = shelltoken.SplitQuotes("echo don’t", shelltoken.Whitespace, shelltoken.SplitSmartQuotes)
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+
+	// shell characters in smart double quotes are found like in double quotes
+	_, err = shelltoken.SplitQuotes("echo “$(id)” x", shelltoken.Whitespace, shelltoken.SplitSmartQuotes|shelltoken.SplitStopOnShellCharacters)
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+
+	argv, err = shelltoken.SplitQuotes("echo ‘$(id)’", shelltoken.Whitespace, shelltoken.SplitSmartQuotes|shelltoken.SplitStopOnShellCharacters)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"echo", "$(id)"}, argv)
+
+	argv, err = shelltoken.SplitQuotes("“a b”", shelltoken.Whitespace, shelltoken.SplitSmartQuotes|shelltoken.SplitNoDoubleQuotes)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"“a", "b”"}, argv)
+}
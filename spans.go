@@ -0,0 +1,73 @@
+package shelltoken
+
+import (
+	"fmt"
+)
+
+// SpanKind identifies the kind of a quoted or escaped region.
+type SpanKind uint8
+
+const (
+	// SpanSingle is a single quoted region including the quotes.
+	SpanSingle SpanKind = iota
+
+	// SpanDouble is a double quoted region including the quotes.
+	SpanDouble
+
+	// SpanEscape is an escape character along with the escaped character.
+	SpanEscape
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case SpanSingle:
+		return "Single"
+	case SpanDouble:
+		return "Double"
+	case SpanEscape:
+		return "Escape"
+	}
+
+	return fmt.Sprintf("SpanKind(%d)", k)
+}
+
+// Span describes a quoted or escaped region of the input.
+type Span struct {
+	Kind  SpanKind // kind of the region
+	Start int      // byte offset of the first character
+	End   int      // byte offset after the last character
+}
+
+// SplitWithSpans works like SplitQuotes and additionally returns all quoted
+// and escaped regions in the order they were closed. Escapes inside double
+// quotes are reported as well, single quoted regions contain no escapes.
+func SplitWithSpans(str, sep string, options ...SplitOption) (argv []string, spans []Span, err error) {
+	cfg := NewConfig(sep, options...)
+	pst := newParseState(&cfg)
+	pst.recordSpans = true
+
+	argv = []string{}
+
+	err = pst.parse(str, func(token string, _, _ int) bool {
+		argv = append(argv, token)
+
+		return true
+	})
+	if err != nil && !pst.continueOnError(err) {
+		return nil, nil, err
+	}
+
+	spans = pst.spans
+	if spans == nil {
+		spans = []Span{}
+	}
+
+	return argv, spans, err
+}
+
+// addSpan records a quoted or escaped region if spans are recorded.
+func (p *parseState) addSpan(kind SpanKind, start, end int) {
+	if p.recordSpans {
+		p.spans = append(p.spans, Span{Kind: kind, Start: start, End: end})
+	}
+}
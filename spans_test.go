@@ -0,0 +1,56 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitWithSpans(t *testing.T) {
+	tests := []struct {
+		in    string
+		argv  []string
+		spans []shelltoken.Span
+	}{
+		{"a b", []string{"a", "b"}, []shelltoken.Span{}},
+		{`a 'b c' "d"`, []string{"a", "b c", "d"}, []shelltoken.Span{
+			{Kind: shelltoken.SpanSingle, Start: 2, End: 7},
+			{Kind: shelltoken.SpanDouble, Start: 8, End: 11},
+		}},
+		{`a\ b "x\"y" '\'`, []string{"a b", `x"y`, `\`}, []shelltoken.Span{
+			{Kind: shelltoken.SpanEscape, Start: 1, End: 3},
+			{Kind: shelltoken.SpanEscape, Start: 7, End: 9},
+			{Kind: shelltoken.SpanDouble, Start: 5, End: 11},
+			{Kind: shelltoken.SpanSingle, Start: 12, End: 15},
+		}},
+		{`"$(id)" '$(id)' \ö`, []string{"$(id)", "$(id)", "ö"}, []shelltoken.Span{
+			{Kind: shelltoken.SpanDouble, Start: 0, End: 7},
+			{Kind: shelltoken.SpanSingle, Start: 8, End: 15},
+			{Kind: shelltoken.SpanEscape, Start: 16, End: 19},
+		}},
+		{`a"'"'"'`, []string{`a'"`}, []shelltoken.Span{
+			{Kind: shelltoken.SpanDouble, Start: 1, End: 4},
+			{Kind: shelltoken.SpanSingle, Start: 4, End: 7},
+		}},
+		{"a\\\xff", []string{"a\uFFFD"}, []shelltoken.Span{
+			{Kind: shelltoken.SpanEscape, Start: 1, End: 3},
+		}},
+	}
+
+	for _, tst := range tests {
+		argv, spans, err := shelltoken.SplitWithSpans(tst.in, shelltoken.Whitespace)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.argv, argv, "Tokenize: %v -> %v", tst.in, argv)
+		assert.Equalf(t, tst.spans, spans, "Spans: %v -> %v", tst.in, spans)
+	}
+
+	argv, spans, err := shelltoken.SplitWithSpans(`a "b`, shelltoken.Whitespace)
+	require.ErrorIs(t, err, shelltoken.ErrUnbalancedQuotes)
+	assert.Nil(t, argv)
+	assert.Nil(t, spans)
+
+	assert.Equal(t, "Double", shelltoken.SpanDouble.String())
+	assert.Equal(t, "SpanKind(9)", shelltoken.SpanKind(9).String())
+}
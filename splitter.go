@@ -0,0 +1,41 @@
+package shelltoken
+
+// Splitter tokenizes strings like SplitQuotes but reuses its internal buffers
+// between calls to reduce allocations.
+// A Splitter is not safe for concurrent use, use one Splitter per goroutine
+// or a sync.Pool.
+type Splitter struct {
+	pst *parseState
+}
+
+// NewSplitter returns a Splitter using the given separator and options.
+func NewSplitter(sep string, options ...SplitOption) *Splitter {
+	cfg := NewConfig(sep, options...)
+
+	return &Splitter{pst: newParseState(&cfg)}
+}
+
+// Split will tokenize text into chunks honoring quotes.
+// It returns the same tokens and errors as SplitQuotes.
+func (s *Splitter) Split(str string) (argv []string, err error) {
+	// also releases the references to str and argv
+	defer s.pst.reset()
+
+	argv = []string{}
+
+	err = s.pst.parse(str, func(token string, _, _ int) bool {
+		argv = append(argv, token)
+
+		return true
+	})
+	if err != nil && !s.pst.continueOnError(err) {
+		return nil, err
+	}
+
+	return argv, err
+}
+
+// Reset clears the state of the Splitter, ex.: before putting it back into a pool.
+func (s *Splitter) Reset() {
+	s.pst.reset()
+}
@@ -0,0 +1,48 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitter(t *testing.T) {
+	tests := []string{
+		"",
+		"a b c",
+		`echo "a b" 'c d' e\ f`,
+		`a "b`,
+		"a | b",
+		"  multi\nline\t'input é'  ",
+		`x "" y`,
+	}
+
+	for _, options := range []shelltoken.SplitOption{shelltoken.SplitNoOptions, shelltoken.SplitStopOnShellCharacters, shelltoken.SplitContinueOnShellCharacters} {
+		splitter := shelltoken.NewSplitter(shelltoken.Whitespace, options)
+
+		// run twice to verify no state is left over from previous calls
+		for range 2 {
+			for _, str := range tests {
+				expect, expectErr := shelltoken.SplitQuotes(str, shelltoken.Whitespace, options)
+				argv, err := splitter.Split(str)
+				assert.Equalf(t, expectErr, err, "error for: %s", str)
+				assert.Equalf(t, expect, argv, "Tokenize: %v -> %v", str, argv)
+			}
+		}
+	}
+}
+
+func TestSplitterReset(t *testing.T) {
+	splitter := shelltoken.NewSplitter(shelltoken.Whitespace)
+
+	_, err := splitter.Split(`a "b c`)
+	require.Error(t, err)
+
+	splitter.Reset()
+
+	argv, err := splitter.Split(`d e`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d", "e"}, argv)
+}
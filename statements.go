@@ -0,0 +1,87 @@
+package shelltoken
+
+// ParseResult contains the env and argv of a single parsed command.
+type ParseResult struct {
+	Env  []string
+	Argv []string
+}
+
+// statementSeparators separate statements in SplitStatements.
+var statementSeparators = []string{";", "\n"}
+
+// SplitStatements splits str on unquoted ";" and newlines into independent
+// statements and tokenizes each of them like SplitLinux does.
+// Quoted or escaped separators stay within a statement, empty statements are skipped.
+func SplitStatements(str string) ([]ParseResult, error) {
+	cfg := NewConfig(" \t\r", linuxOptions)
+	cfg.Operators = statementSeparators
+
+	tokens, err := splitTokens(str, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []ParseResult{}
+	statement := []string{}
+
+	for _, token := range tokens {
+		if !isStatementSeparator(str[token.Start:token.End]) {
+			statement = append(statement, token.Value)
+
+			continue
+		}
+
+		if len(statement) > 0 {
+			results = append(results, newParseResult(statement))
+			statement = []string{}
+		}
+	}
+
+	if len(statement) > 0 {
+		results = append(results, newParseResult(statement))
+	}
+
+	return results, nil
+}
+
+// SplitCommands splits str into commands like SplitStatements and returns the argv
+// of each command. Environment assignments are removed from the argv, statements
+// consisting only of assignments have no command and are skipped.
+func SplitCommands(str string) ([][]string, error) {
+	statements, err := SplitStatements(str)
+	if err != nil {
+		return nil, err
+	}
+
+	commands := make([][]string, 0, len(statements))
+
+	for _, statement := range statements {
+		if len(statement.Argv) > 0 {
+			commands = append(commands, statement.Argv)
+		}
+	}
+
+	return commands, nil
+}
+
+func newParseResult(tokens []string) ParseResult {
+	envIndex, cmd := extractEnvIndex(tokens)
+
+	env := make([]string, 0, len(envIndex))
+	for _, i := range envIndex {
+		env = append(env, tokens[i])
+	}
+
+	return ParseResult{Env: env, Argv: tokens[cmd:]}
+}
+
+// isStatementSeparator returns true if raw is an unquoted statement separator.
+func isStatementSeparator(raw string) bool {
+	for _, sep := range statementSeparators {
+		if raw == sep {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []shelltoken.ParseResult
+	}{
+		{"", []shelltoken.ParseResult{}},
+		{" ; ;\n", []shelltoken.ParseResult{}},
+		{"echo a", []shelltoken.ParseResult{
+			{Env: []string{}, Argv: []string{"echo", "a"}},
+		}},
+		{"echo a; echo b", []shelltoken.ParseResult{
+			{Env: []string{}, Argv: []string{"echo", "a"}},
+			{Env: []string{}, Argv: []string{"echo", "b"}},
+		}},
+		{"A=1 cmd x;B=2 cmd y\n\ncmd z;", []shelltoken.ParseResult{
+			{Env: []string{"A=1"}, Argv: []string{"cmd", "x"}},
+			{Env: []string{"B=2"}, Argv: []string{"cmd", "y"}},
+			{Env: []string{}, Argv: []string{"cmd", "z"}},
+		}},
+		{`echo "a;b" 'c;d' ";"`, []shelltoken.ParseResult{
+			{Env: []string{}, Argv: []string{"echo", "a;b", "c;d", ";"}},
+		}},
+		{"echo 'a\nb'", []shelltoken.ParseResult{
+			{Env: []string{}, Argv: []string{"echo", "a\nb"}},
+		}},
+		{"A=1; echo", []shelltoken.ParseResult{
+			{Env: []string{"A=1"}, Argv: []string{}},
+			{Env: []string{}, Argv: []string{"echo"}},
+		}},
+		{"export A=1; echo a", []shelltoken.ParseResult{
+			{Env: []string{"A=1"}, Argv: []string{}},
+			{Env: []string{}, Argv: []string{"echo", "a"}},
+		}},
+		{"export A=1 export B=2", []shelltoken.ParseResult{
+			{Env: []string{"A=1", "B=2"}, Argv: []string{}},
+		}},
+		{"export A=1 cmd", []shelltoken.ParseResult{
+			{Env: []string{"A=1"}, Argv: []string{"cmd"}},
+		}},
+	}
+
+	for _, tst := range tests {
+		res, err := shelltoken.SplitStatements(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, res, "statements of: %s", tst.in)
+	}
+
+	_, err := shelltoken.SplitStatements("echo a; echo b | wc")
+	require.Error(t, err)
+
+	_, err = shelltoken.SplitStatements("echo 'a; echo b")
+	require.EqualError(t, err, "unbalanced ' quote opened at position 5")
+}
+
+func TestSplitCommands(t *testing.T) {
+	tests := []struct {
+		in  string
+		res [][]string
+	}{
+		{"", [][]string{}},
+		{"echo a; echo b", [][]string{{"echo", "a"}, {"echo", "b"}}},
+		{"echo a;\n;echo b;", [][]string{{"echo", "a"}, {"echo", "b"}}},
+		{"A=1 cmd x\nB=2; cmd y", [][]string{{"cmd", "x"}, {"cmd", "y"}}},
+		{"echo 'a; echo b'\necho \"c\nd\"", [][]string{{"echo", "a; echo b"}, {"echo", "c\nd"}}},
+		{`echo "a;" b`, [][]string{{"echo", "a;", "b"}}},
+	}
+
+	for _, tst := range tests {
+		res, err := shelltoken.SplitCommands(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, res, "commands of: %s", tst.in)
+	}
+
+	res, err := shelltoken.SplitCommands("echo a; echo $(id)")
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+	assert.Nil(t, res)
+}
@@ -0,0 +1,106 @@
+package shelltoken
+
+// isProcessSubstitution returns true if a process substitution "<(" or ">(" starts at pos.
+func (p *parseState) isProcessSubstitution(char rune, pos int) bool {
+	if !p.keepProcSubst || p.inSingleQuotes || p.inDoubleQuotes || (char != '<' && char != '>') {
+		return false
+	}
+
+	return pos+1 < len(p.str) && p.str[pos+1] == '('
+}
+
+// isCommandSubstitution returns true if a command substitution "$(" or "`" starts at pos.
+func (p *parseState) isCommandSubstitution(char rune, pos int) bool {
+	if !p.keepCmdSubst || p.inSingleQuotes || p.inDoubleQuotes {
+		return false
+	}
+
+	switch char {
+	case '`':
+		return true
+	case '$':
+		return pos+1 < len(p.str) && p.str[pos+1] == '('
+	}
+
+	return false
+}
+
+// addCommandSubstitution adds the command substitution starting at pos verbatim to the current token.
+// Returns false if the substitution is not closed.
+func (p *parseState) addCommandSubstitution(char rune, pos int) bool {
+	if char == '$' {
+		return p.addSubstitution(pos, pos+2)
+	}
+
+	end := pos + 1
+	for ; end < len(p.str) && p.str[end] != '`'; end++ {
+		if p.str[end] == '\\' {
+			// skip escaped character
+			end++
+		}
+	}
+
+	if end >= len(p.str) {
+		return false
+	}
+
+	p.markToken(pos)
+	p.hasToken = true
+	p.token.WriteString(p.str[pos : end+1])
+	p.skip = end + 1
+
+	return true
+}
+
+// addSubstitution adds the substitution starting at pos verbatim to the current token.
+// The content starts at start, right after the opening parenthesis.
+// Returns false if there is no matching closing parenthesis.
+func (p *parseState) addSubstitution(pos, start int) bool {
+	end := matchingParen(p.str, start)
+	if end == -1 {
+		return false
+	}
+
+	p.markToken(pos)
+	p.hasToken = true
+	p.token.WriteString(p.str[pos : end+1])
+	p.skip = end + 1
+
+	return true
+}
+
+// matchingParen returns the position of the parenthesis closing the one right before start
+// or -1 if there is none. Quoted and escaped parentheses are ignored.
+func matchingParen(str string, start int) int {
+	depth := 1
+	quote := byte(0)
+
+	for i := start; i < len(str); i++ {
+		char := str[i]
+
+		switch {
+		case quote == '\'':
+			if char == '\'' {
+				quote = 0
+			}
+		case char == '\\':
+			// skip escaped character
+			i++
+		case quote == '"':
+			if char == '"' {
+				quote = 0
+			}
+		case char == '\'', char == '"':
+			quote = char
+		case char == '(':
+			depth++
+		case char == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,76 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitKeepProcessSubstitution(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{`diff <(sort a) <(sort b)`, []string{"diff", "<(sort a)", "<(sort b)"}},
+		{`tee >(gzip > out.gz)`, []string{"tee", ">(gzip > out.gz)"}},
+		{`diff <(cat <(echo (a)) ')') x`, []string{"diff", `<(cat <(echo (a)) ')')`, "x"}},
+		{`cmd <(echo "a)" \)) x`, []string{"cmd", `<(echo "a)" \))`, "x"}},
+		{`cmd "<(a)" '>(b)'`, []string{"cmd", "<(a)", ">(b)"}},
+		{`cmd a<(b)c`, []string{"cmd", "a<(b)c"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitKeepProcessSubstitution|shelltoken.SplitStopOnShellCharacters)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	argv, err := shelltoken.SplitQuotes(`diff <(sort a`, shelltoken.Whitespace, shelltoken.SplitKeepProcessSubstitution)
+	require.EqualError(t, err, "unbalanced ( quote opened at position 5")
+	assert.Nil(t, argv)
+
+	// other shell characters are still reported
+	_, err = shelltoken.SplitQuotes(`diff <(sort a) | wc`, shelltoken.Whitespace, shelltoken.SplitKeepProcessSubstitution|shelltoken.SplitStopOnShellCharacters)
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+
+	// without the option process substitutions are shell characters
+	_, err = shelltoken.SplitQuotes(`diff <(sort a)`, shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters)
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+}
+
+func TestSplitKeepCommandSubstitution(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{`echo $(date) and`, []string{"echo", "$(date)", "and"}},
+		{"echo `whoami`", []string{"echo", "`whoami`"}},
+		{`echo $(echo $(id))`, []string{"echo", "$(echo $(id))"}},
+		{`echo $(echo "a b" ')') x`, []string{"echo", `$(echo "a b" ')')`, "x"}},
+		{"echo `echo \\` a` x", []string{"echo", "`echo \\` a`", "x"}},
+		{"echo a$(b)c-`d`", []string{"echo", "a$(b)c-`d`"}},
+		{"echo '$(date)' '`whoami`'", []string{"echo", "$(date)", "`whoami`"}},
+	}
+
+	for _, tst := range tests {
+		argv, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, shelltoken.SplitKeepCommandSubstitution|shelltoken.SplitStopOnShellCharacters)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.res, argv, "Tokenize: %v -> %v", tst.in, argv)
+	}
+
+	// substitutions in double quotes are not captured
+	argv, err := shelltoken.SplitQuotes(`echo "$(date)"`, shelltoken.Whitespace, shelltoken.SplitKeepCommandSubstitution)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"echo", "$(date)"}, argv)
+
+	_, err = shelltoken.SplitQuotes(`echo "$(date)"`, shelltoken.Whitespace, shelltoken.SplitKeepCommandSubstitution|shelltoken.SplitStopOnShellCharacters)
+	require.ErrorIs(t, err, shelltoken.ErrShellCharacters)
+
+	_, err = shelltoken.SplitQuotes("echo $(date", shelltoken.Whitespace, shelltoken.SplitKeepCommandSubstitution)
+	require.EqualError(t, err, "unbalanced ( quote opened at position 5")
+
+	_, err = shelltoken.SplitQuotes("echo `date", shelltoken.Whitespace, shelltoken.SplitKeepCommandSubstitution)
+	require.EqualError(t, err, "unbalanced ` quote opened at position 5")
+}
@@ -0,0 +1,349 @@
+package shelltoken
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const tokenizerReadSize = 4096
+
+// Tokenizer reads tokens from an io.Reader.
+// It honors the same quote and escape rules as SplitQuotes but only buffers
+// enough input to complete the current token. With SplitHeredoc, the input
+// is buffered from the first here-document up to the end of the input.
+type Tokenizer struct {
+	reader   io.Reader
+	cfg      Config
+	input    strings.Builder // read input, the buffer starts at start
+	start    int             // number of bytes of input consumed
+	pst      *parseState     // parse state of the incomplete token at the start of the buffer
+	offset   int             // number of bytes consumed before the buffer
+	runes    int             // number of runes consumed before the buffer
+	line     int             // number of lines consumed before the buffer
+	col      int             // number of runes consumed since the last line break before the buffer
+	eof      bool            // reader is exhausted
+	envDone  bool            // leading env assignments have ended, see SplitCheckEnvValues
+	field    bool            // the last token is a field ending at the separator starting the buffer
+	queue    []string        // remaining tokens after the reader is exhausted
+	err      error           // error to return after the queue
+	shellErr error           // delayed error for SplitContinueOnShellCharacters
+}
+
+// NewTokenizer creates a new Tokenizer reading from r.
+// Options are a list of SplitOption(s) or a bitmask of SplitOption(s).
+func NewTokenizer(r io.Reader, sep string, options ...SplitOption) *Tokenizer {
+	return &Tokenizer{
+		reader: r,
+		cfg:    NewConfig(sep, options...),
+	}
+}
+
+// Next returns the next token. It returns io.EOF if there are no more tokens.
+// Parse errors are returned with positions relative to the start of the stream.
+// An UnbalancedQuotesError is returned at the end of the stream if a quote never closes.
+func (t *Tokenizer) Next() (string, error) {
+	for !t.eof {
+		token, ok, err := t.nextBuffered()
+		if err != nil {
+			t.eof = true
+			t.err = io.EOF
+
+			return "", err
+		}
+
+		if ok {
+			return token, nil
+		}
+
+		err = t.fill()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if len(t.queue) > 0 {
+		token := t.queue[0]
+		t.queue = t.queue[1:]
+
+		return token, nil
+	}
+
+	err := t.err
+	t.err = io.EOF
+
+	return "", err
+}
+
+// nextBuffered returns the next token if it is complete in the current buffer.
+// The parse state of an incomplete token is kept, so the next call only parses
+// the input added in between.
+func (t *Tokenizer) nextBuffered() (token string, ok bool, err error) {
+	pst := t.pst
+	if pst == nil {
+		pst = t.newParseState()
+		pst.partial = true
+	}
+
+	t.pst = nil
+	buf := t.buffered()
+	lookahead := t.lookahead(pst)
+	end := -1
+
+	err = pst.parsePartial(buf, lookahead, func(tok string, _, tokEnd int) bool {
+		token = tok
+		end = tokEnd
+
+		return false
+	})
+
+	switch {
+	case err != nil:
+		return "", false, t.shiftErrorPos(err)
+	case end == -1:
+		// continue the token with more input
+		t.pst = pst
+
+		return "", false, nil
+	case end+lookahead > len(buf):
+		// the token might continue or change with more input, ex.: a run of separators
+		return "", false, nil
+	}
+
+	t.envDone = !pst.envPhase
+	t.field = pst.lastField
+
+	if pst.contShell && pst.firstShellPos != -1 && t.shellErr == nil {
+		t.shellErr = t.shiftErrorPos(pst.shellError())
+	}
+
+	t.consume(end)
+
+	return token, true, nil
+}
+
+// fill reads the next chunk from the reader and tokenizes the remaining
+// buffer once the reader is exhausted.
+func (t *Tokenizer) fill() error {
+	chunk := make([]byte, tokenizerReadSize)
+
+	num, err := t.reader.Read(chunk)
+	t.input.Write(chunk[:num])
+
+	switch {
+	case errors.Is(err, io.EOF):
+		t.finish()
+
+		return nil
+	case err != nil:
+		return err
+	}
+
+	return nil
+}
+
+// finish tokenizes the remaining buffer.
+func (t *Tokenizer) finish() {
+	t.eof = true
+	t.err = io.EOF
+
+	pst := t.newParseState()
+
+	err := pst.parse(t.buffered(), func(token string, _, _ int) bool {
+		t.queue = append(t.queue, token)
+
+		return true
+	})
+
+	if err = t.finalError(err); err != nil {
+		t.err = err
+	}
+
+	t.input.Reset()
+	t.start = 0
+}
+
+// finalError returns the error of the stream after parsing the remaining buffer failed with err.
+// The first shell character of the stream is reported, even if the remaining buffer contains
+// another one, like SplitQuotes does with SplitContinueOnShellCharacters.
+func (t *Tokenizer) finalError(err error) error {
+	var shellErr *ShellCharactersFoundError
+
+	switch {
+	case t.shellErr != nil && (err == nil || errors.As(err, &shellErr)):
+		return t.shellErr
+	case err != nil:
+		return t.shiftErrorPos(err)
+	}
+
+	return nil
+}
+
+// newParseState returns a parse state for the next token, which continues
+// the leading env assignments of the previous tokens.
+func (t *Tokenizer) newParseState() *parseState {
+	pst := newParseState(&t.cfg)
+	pst.envPhase = !t.envDone
+	pst.afterField = t.field
+
+	return pst
+}
+
+// buffered returns the input which has not been consumed yet.
+func (t *Tokenizer) buffered() string {
+	return t.input.String()[t.start:]
+}
+
+// lookahead returns the number of bytes required after a token to be sure it is complete.
+func (t *Tokenizer) lookahead(pst *parseState) int {
+	num := utf8.UTFMax
+	if len(pst.rawStart) > num {
+		num = len(pst.rawStart)
+	}
+
+	if len(pst.operators) > 0 && len(pst.operators[0]) > num {
+		num = len(pst.operators[0])
+	}
+
+	return num
+}
+
+// consume removes num bytes from the start of the buffer.
+func (t *Tokenizer) consume(num int) {
+	consumed := t.buffered()[:num]
+	t.runes += utf8.RuneCountInString(consumed)
+
+	if lines := strings.Count(consumed, "\n"); lines > 0 {
+		t.line += lines
+		t.col = 0
+		consumed = consumed[strings.LastIndexByte(consumed, '\n')+1:]
+	}
+
+	t.col += utf8.RuneCountInString(consumed)
+	t.start += num
+	t.offset += num
+
+	// drop the consumed input once it makes up most of the buffer
+	if t.start > tokenizerReadSize && t.start > t.input.Len()/2 {
+		rest := t.buffered()
+		t.input.Reset()
+		t.input.WriteString(rest)
+		t.start = 0
+	}
+}
+
+// shiftErrorPos returns err with all positions relative to the start of the stream.
+func (t *Tokenizer) shiftErrorPos(err error) error {
+	var shellErr *ShellCharactersFoundError
+	var quoteErr *MidWordQuoteError
+	var unbalancedErr *UnbalancedQuotesError
+	var charErr *InvalidCharacterError
+	var strictErr *UnexpectedQuoteError
+
+	offset := t.offset
+
+	switch {
+	case offset == 0:
+		return err
+	case errors.As(err, &shellErr):
+		shifted := *shellErr
+		shifted.pos += offset
+		shifted.runePos += t.runes
+		if shifted.Line == 1 {
+			shifted.Col += t.col
+		}
+
+		shifted.Line += t.line
+
+		return &shifted
+	case errors.As(err, &quoteErr):
+		return &MidWordQuoteError{Pos: quoteErr.Pos + offset}
+	case errors.As(err, &unbalancedErr):
+		return &UnbalancedQuotesError{Quote: unbalancedErr.Quote, Pos: unbalancedErr.Pos + offset}
+	case errors.As(err, &charErr):
+		return &InvalidCharacterError{Char: charErr.Char, Pos: charErr.Pos + offset}
+	case errors.As(err, &strictErr):
+		return &UnexpectedQuoteError{Quote: strictErr.Quote, Pos: strictErr.Pos + offset}
+	}
+
+	return err
+}
+
+// ScanTokens returns a bufio.SplitFunc which splits the input of a bufio.Scanner
+// into tokens using the same rules as NewTokenizer. A token is only returned once
+// it is complete, so more data is requested if it might continue after the end
+// of the buffer, ex.: within an open quote. An unbalanced quote at the end of the
+// input is returned as error by the scanner. Error positions are relative to the
+// start of the input. The split function keeps state and must not be shared
+// between scanners.
+func ScanTokens(sep string, options ...SplitOption) bufio.SplitFunc {
+	scanner := &tokenScanner{
+		tokenizer: Tokenizer{cfg: NewConfig(sep, options...)},
+	}
+
+	return scanner.split
+}
+
+// tokenScanner implements ScanTokens on top of the Tokenizer position tracking.
+type tokenScanner struct {
+	tokenizer Tokenizer
+	queue     []Token // remaining tokens once the input is complete
+	queued    bool    // the remaining input has been tokenized
+	consumed  int     // bytes of the remaining input returned so far
+	err       error   // error to return after the queue
+}
+
+func (s *tokenScanner) split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF {
+		return s.splitFinal(data)
+	}
+
+	// data starts with the buffered input, only new data is added
+	t := &s.tokenizer
+	if buffered := t.input.Len() - t.start; len(data) > buffered {
+		t.input.Write(data[buffered:])
+	}
+
+	offset := t.offset
+
+	tok, ok, err := t.nextBuffered()
+	switch {
+	case err != nil:
+		return 0, nil, err
+	case !ok:
+		// request more data
+		return 0, nil, nil
+	}
+
+	return t.offset - offset, append([]byte{}, tok...), nil
+}
+
+// splitFinal returns the tokens of the remaining input after the reader is exhausted.
+func (s *tokenScanner) splitFinal(data []byte) (advance int, token []byte, err error) {
+	if !s.queued {
+		s.queued = true
+		t := &s.tokenizer
+		pst := t.newParseState()
+
+		err = pst.parse(string(data), func(tok string, _, end int) bool {
+			s.queue = append(s.queue, Token{Value: tok, End: end})
+
+			return true
+		})
+
+		s.err = t.finalError(err)
+	}
+
+	if len(s.queue) == 0 {
+		return len(data), nil, s.err
+	}
+
+	next := s.queue[0]
+	s.queue = s.queue[1:]
+	advance = next.End - s.consumed
+	s.consumed = next.End
+
+	return advance, append([]byte{}, next.Value...), nil
+}
@@ -0,0 +1,227 @@
+package shelltoken_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAllTokens(tokenizer *shelltoken.Tokenizer) (tokens []string, err error) {
+	tokens = []string{}
+
+	for {
+		token, err := tokenizer.Next()
+		if errors.Is(err, io.EOF) {
+			return tokens, nil
+		}
+
+		if err != nil {
+			return tokens, err
+		}
+
+		tokens = append(tokens, token)
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	tests := []struct {
+		in      string
+		options shelltoken.SplitOption
+	}{
+		{"", shelltoken.SplitNoOptions},
+		{"a b c", shelltoken.SplitNoOptions},
+		{`  echo "a b"  'c d'e\ f  `, shelltoken.SplitNoOptions},
+		{"ls -l\necho 'multi\nline' \"é ü\"\n", shelltoken.SplitNoOptions},
+		{`a  "b c" d`, shelltoken.SplitKeepSeparator | shelltoken.SplitKeepQuotes},
+		{`"a""b" 'c'`, shelltoken.SplitDoubledQuotes},
+		{strings.Repeat(`word "quoted text" `, 500), shelltoken.SplitNoOptions},
+		{"echo \"a\\\nb\" c\\\nd", shelltoken.SplitStrictPOSIX},
+		{"a\\\\\nb", shelltoken.SplitLineContinuation},
+		{"a '" + strings.Repeat("x y\n", 20000) + "' b", shelltoken.SplitNoOptions},
+		{"A=1 export B='x y' cmd C=$(x) `y`", shelltoken.SplitCheckEnvValues},
+		{"ls -l\ncat <<EOF x\nbody $y\nEOF\nls <<-'END'\n\tz\nEND\n", shelltoken.SplitHeredoc},
+	}
+
+	for _, tst := range tests {
+		expect, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, tst.options)
+		require.NoError(t, err)
+
+		tokens, err := readAllTokens(shelltoken.NewTokenizer(strings.NewReader(tst.in), shelltoken.Whitespace, tst.options))
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, expect, tokens, "Tokenize: %v -> %v", tst.in, tokens)
+
+		tokens, err = readAllTokens(shelltoken.NewTokenizer(iotest.OneByteReader(strings.NewReader(tst.in)), shelltoken.Whitespace, tst.options))
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, expect, tokens, "Tokenize one byte reader: %v -> %v", tst.in, tokens)
+	}
+}
+
+func TestTokenizerKeepEmptyFields(t *testing.T) {
+	tests := []struct {
+		in      string
+		sep     string
+		options shelltoken.SplitOption
+	}{
+		{strings.Repeat("x,,", 3000) + "y", ",", shelltoken.SplitKeepEmptyFields},
+		{",," + strings.Repeat("x,'',", 2000) + ",", ",", shelltoken.SplitKeepEmptyFields},
+		{strings.Repeat("a,,b,", 2000), ",", shelltoken.SplitKeepEmptyFields | shelltoken.SplitKeepSeparator},
+		{strings.Repeat("a  b ", 2000), shelltoken.Whitespace, shelltoken.SplitKeepEmptyFields},
+	}
+
+	for _, tst := range tests {
+		expect, err := shelltoken.SplitQuotes(tst.in, tst.sep, tst.options)
+		require.NoError(t, err)
+
+		tokens, err := readAllTokens(shelltoken.NewTokenizer(strings.NewReader(tst.in), tst.sep, tst.options))
+		require.NoError(t, err)
+		assert.Equalf(t, expect, tokens, "Tokenize: %.20q", tst.in)
+
+		tokens, err = readAllTokens(shelltoken.NewTokenizer(iotest.OneByteReader(strings.NewReader(tst.in)), tst.sep, tst.options))
+		require.NoError(t, err)
+		assert.Equalf(t, expect, tokens, "Tokenize one byte reader: %.20q", tst.in)
+
+		for _, bufSize := range []int{1, 4096} {
+			tokens, err = scanAllTokens(strings.NewReader(tst.in), bufSize, tst.sep, tst.options)
+			require.NoError(t, err)
+			assert.Equalf(t, expect, tokens, "Scan (buffer %d): %.20q", bufSize, tst.in)
+		}
+	}
+}
+
+func TestTokenizerErrors(t *testing.T) {
+	tokenizer := shelltoken.NewTokenizer(iotest.OneByteReader(strings.NewReader(`a "b c`)), shelltoken.Whitespace)
+	token, err := tokenizer.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "a", token)
+
+	_, err = tokenizer.Next()
+	require.EqualError(t, err, `unbalanced " quote opened at position 2`)
+
+	_, err = tokenizer.Next()
+	require.ErrorIs(t, err, io.EOF)
+
+	input := strings.Repeat("word ", 2000) + "$(ls)"
+	tokens, err := readAllTokens(shelltoken.NewTokenizer(strings.NewReader(input), shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters))
+	require.EqualError(t, err, "shell character '$' at line 1 column 10001 (offset 10000, command substitution)")
+	assert.Len(t, tokens, 2000)
+
+	tokens, err = readAllTokens(shelltoken.NewTokenizer(strings.NewReader("a $b c"), shelltoken.Whitespace, shelltoken.SplitContinueOnShellCharacters))
+	require.EqualError(t, err, "shell character '$' at line 1 column 3 (offset 2, variable expansion)")
+	assert.Equal(t, []string{"a", "$b", "c"}, tokens)
+
+	// the first shell character is reported like by SplitQuotes
+	input = "a|b c d e f g h i j k>l"
+	expect, err := shelltoken.SplitQuotes(input, shelltoken.Whitespace, shelltoken.SplitContinueOnShellCharacters)
+	require.EqualError(t, err, "shell character '|' at line 1 column 2 (offset 1)")
+
+	tokens, err = readAllTokens(shelltoken.NewTokenizer(iotest.OneByteReader(strings.NewReader(input)), shelltoken.Whitespace, shelltoken.SplitContinueOnShellCharacters))
+	require.EqualError(t, err, "shell character '|' at line 1 column 2 (offset 1)")
+	assert.Equal(t, expect, tokens)
+
+	input = strings.Repeat("word\n", 1000) + "äö ü|x"
+	_, err = readAllTokens(shelltoken.NewTokenizer(iotest.OneByteReader(strings.NewReader(input)), shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters))
+	require.EqualError(t, err, "shell character '|' at line 1001 column 5 (offset 5007)")
+
+	shellErr := &shelltoken.ShellCharactersFoundError{}
+	require.ErrorAs(t, err, &shellErr)
+	assert.Equal(t, 5004, shellErr.RuneOffset())
+
+	input = strings.Repeat("word ", 2000) + "a\x00"
+	_, err = readAllTokens(shelltoken.NewTokenizer(strings.NewReader(input), shelltoken.Whitespace, shelltoken.SplitRejectNul))
+	require.EqualError(t, err, `invalid character '\x00' at position 10001`)
+
+	_, err = readAllTokens(shelltoken.NewTokenizer(iotest.ErrReader(io.ErrUnexpectedEOF), shelltoken.Whitespace))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	input = strings.Repeat("A=1 ", 2000) + "B=$(id) cmd"
+	tokens, err = readAllTokens(shelltoken.NewTokenizer(strings.NewReader(input), shelltoken.Whitespace, shelltoken.SplitCheckEnvValues))
+	require.EqualError(t, err, "shell character '$' at line 1 column 8003 (offset 8002, command substitution)")
+	assert.Len(t, tokens, 2000)
+
+	// tokens before the first here-document are returned before the end of the input
+	reader := io.MultiReader(strings.NewReader("echo a b <<EOF\nbody\n"), iotest.ErrReader(io.ErrUnexpectedEOF))
+	tokens, err = readAllTokens(shelltoken.NewTokenizer(reader, shelltoken.Whitespace, shelltoken.SplitHeredoc))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, []string{"echo", "a", "b"}, tokens)
+
+	// tokens are returned before the end of the input with SplitCheckEnvValues
+	reader = io.MultiReader(strings.NewReader("A=1 cmd $(x)     "), iotest.ErrReader(io.ErrUnexpectedEOF))
+	tokens, err = readAllTokens(shelltoken.NewTokenizer(reader, shelltoken.Whitespace, shelltoken.SplitCheckEnvValues))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, []string{"A=1", "cmd", "$(x)"}, tokens)
+}
+
+func scanAllTokens(reader io.Reader, bufSize int, sep string, options ...shelltoken.SplitOption) (tokens []string, err error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, bufSize), 1024*1024)
+	scanner.Split(shelltoken.ScanTokens(sep, options...))
+
+	tokens = []string{}
+	for scanner.Scan() {
+		tokens = append(tokens, scanner.Text())
+	}
+
+	return tokens, scanner.Err()
+}
+
+func TestScanTokens(t *testing.T) {
+	tests := []struct {
+		in      string
+		options shelltoken.SplitOption
+	}{
+		{"", shelltoken.SplitNoOptions},
+		{"   ", shelltoken.SplitNoOptions},
+		{"a b c", shelltoken.SplitNoOptions},
+		{`  echo "a b"  'c d'e\ f  "" `, shelltoken.SplitNoOptions},
+		{"ls -l\necho 'multi\nline' \"é ü\"\n", shelltoken.SplitNoOptions},
+		{`a  "b c" d`, shelltoken.SplitKeepSeparator | shelltoken.SplitKeepQuotes},
+		{strings.Repeat(`word "quoted text" `, 500), shelltoken.SplitNoOptions},
+		{"cat <<EOF x\nbody $y\nEOF\nls", shelltoken.SplitHeredoc},
+		{"A=1 cmd B=$(x)", shelltoken.SplitCheckEnvValues},
+		{"a '" + strings.Repeat("x y\n", 20000) + "' b", shelltoken.SplitNoOptions},
+	}
+
+	for _, tst := range tests {
+		expect, err := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, tst.options)
+		require.NoError(t, err)
+
+		for _, bufSize := range []int{1, 3, 4096} {
+			tokens, err := scanAllTokens(strings.NewReader(tst.in), bufSize, shelltoken.Whitespace, tst.options)
+			require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+			assert.Equalf(t, expect, tokens, "Scan (buffer %d): %v -> %v", bufSize, tst.in, tokens)
+
+			tokens, err = scanAllTokens(iotest.OneByteReader(strings.NewReader(tst.in)), bufSize, shelltoken.Whitespace, tst.options)
+			require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+			assert.Equalf(t, expect, tokens, "Scan one byte reader (buffer %d): %v -> %v", bufSize, tst.in, tokens)
+		}
+	}
+}
+
+func TestScanTokensErrors(t *testing.T) {
+	tokens, err := scanAllTokens(iotest.OneByteReader(strings.NewReader(`a b "c d`)), 2, shelltoken.Whitespace)
+	require.EqualError(t, err, `unbalanced " quote opened at position 4`)
+	assert.Equal(t, []string{"a", "b"}, tokens)
+
+	input := strings.Repeat("word ", 2000) + "$(ls)"
+	tokens, err = scanAllTokens(strings.NewReader(input), 16, shelltoken.Whitespace, shelltoken.SplitStopOnShellCharacters)
+	require.EqualError(t, err, "shell character '$' at line 1 column 10001 (offset 10000, command substitution)")
+	assert.Len(t, tokens, 2000)
+
+	tokens, err = scanAllTokens(strings.NewReader("a $b c"), 2, shelltoken.Whitespace, shelltoken.SplitContinueOnShellCharacters)
+	require.EqualError(t, err, "shell character '$' at line 1 column 3 (offset 2, variable expansion)")
+	assert.Equal(t, []string{"a", "$b", "c"}, tokens)
+
+	tokens, err = scanAllTokens(strings.NewReader("a|b c d e f g h i j k>l"), 2, shelltoken.Whitespace, shelltoken.SplitContinueOnShellCharacters)
+	require.EqualError(t, err, "shell character '|' at line 1 column 2 (offset 1)")
+	assert.Len(t, tokens, 10)
+
+	_, err = scanAllTokens(iotest.ErrReader(io.ErrUnexpectedEOF), 16, shelltoken.Whitespace)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
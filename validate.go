@@ -0,0 +1,14 @@
+package shelltoken
+
+// Validate checks str with the same rules as SplitQuotes and returns the same
+// errors, ex.: UnbalancedQuotesError or ShellCharactersFoundError, without
+// building the list of tokens.
+func Validate(str, sep string, options ...SplitOption) error {
+	cfg := NewConfig(sep, options...)
+	pst := newParseState(&cfg)
+	pst.borrowTokens = true
+
+	return pst.parse(str, func(string, int, int) bool {
+		return true
+	})
+}
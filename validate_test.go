@@ -0,0 +1,30 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		in      string
+		options shelltoken.SplitOption
+	}{
+		{"", 0},
+		{`echo "a b" 'c d' e\ f`, 0},
+		{`echo "a b`, 0},
+		{`echo 'a b`, shelltoken.SplitStopOnShellCharacters},
+		{`echo a | b`, shelltoken.SplitStopOnShellCharacters},
+		{`echo a | b`, shelltoken.SplitContinueOnShellCharacters},
+		{`echo "$(id)"`, shelltoken.SplitStopOnShellCharacters},
+		{`echo a | b`, 0},
+	}
+
+	for _, tst := range tests {
+		_, expect := shelltoken.SplitQuotes(tst.in, shelltoken.Whitespace, tst.options)
+		err := shelltoken.Validate(tst.in, shelltoken.Whitespace, tst.options)
+		assert.Equalf(t, expect, err, "Validate: %s", tst.in)
+	}
+}
@@ -0,0 +1,60 @@
+package shelltoken
+
+import "strings"
+
+// SplitWindowsArgv will tokenize a string exactly like CommandLineToArgvW does it.
+// The first argument is the program name and follows special rules:
+// - double quotes are removed and toggle quoting.
+// - it ends at the first space or tab outside of quotes.
+// - backslashes are always literal, they do not escape double quotes.
+// A command line starting with whitespace results in an empty program name.
+// All following arguments use the rules described in SplitGoExecWindows:
+// - 2n backslashes followed by a double quote produce n backslashes and toggle quoting.
+// - 2n+1 backslashes followed by a double quote produce n backslashes and a literal quote.
+// - two double quotes within a quoted section produce a literal quote.
+// Unlike SplitWindows, shell characters and env assignments have no special meaning.
+// An empty string results in an empty argv.
+func SplitWindowsArgv(str string) (argv []string) {
+	argv = []string{}
+	if str == "" {
+		return argv
+	}
+
+	var prog string
+	prog, str = windowsProgramName(str)
+	argv = append(argv, prog)
+
+	for str != "" {
+		if str[0] == ' ' || str[0] == '\t' {
+			str = str[1:]
+
+			continue
+		}
+
+		var arg string
+		arg, str = nextGoExecWindowsArg(str)
+		argv = append(argv, arg)
+	}
+
+	return argv
+}
+
+// windowsProgramName returns the program name and the remaining command line.
+func windowsProgramName(str string) (prog, rest string) {
+	token := strings.Builder{}
+	inQuotes := false
+
+	for ; str != ""; str = str[1:] {
+		char := str[0]
+		switch {
+		case char == '"':
+			inQuotes = !inQuotes
+		case (char == ' ' || char == '\t') && !inQuotes:
+			return token.String(), str[1:]
+		default:
+			token.WriteByte(char)
+		}
+	}
+
+	return token.String(), ""
+}
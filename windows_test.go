@@ -0,0 +1,64 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitWindowsArgv(t *testing.T) {
+	tests := []struct {
+		in  string
+		res []string
+	}{
+		{``, []string{}},
+		{`prog`, []string{"prog"}},
+		{` prog a`, []string{"", "prog", "a"}},
+		{`"C:\Program Files\a.exe" -x`, []string{`C:\Program Files\a.exe`, "-x"}},
+		{`"C:\Program Files\a.exe"-x y`, []string{`C:\Program Files\a.exe-x`, "y"}},
+		{`C:\"Program Files"\a.exe -x`, []string{`C:\Program Files\a.exe`, "-x"}},
+		{`"C:\Program Files\a.exe`, []string{`C:\Program Files\a.exe`}},
+		{`C:\dir\\a.exe\ x`, []string{`C:\dir\\a.exe\`, "x"}},
+		// examples from the microsoft documentation of the argument parsing rules
+		{`prog "a b c" d e`, []string{"prog", "a b c", "d", "e"}},
+		{`prog "ab\"c" "\\" d`, []string{"prog", `ab"c`, `\`, "d"}},
+		{`prog a\\\b d"e f"g h`, []string{"prog", `a\\\b`, "de fg", "h"}},
+		{`prog a\\\"b c d`, []string{"prog", `a\"b`, "c", "d"}},
+		{`prog a\\\\"b c" d e`, []string{"prog", `a\\b c`, "d", "e"}},
+		{`prog a"b"" c d`, []string{"prog", `ab"`, "c", "d"}},
+		// trailing backslashes in quoted paths
+		{`prog "C:\dir\\" x`, []string{"prog", `C:\dir\`, "x"}},
+		{`prog "C:\dir\" x`, []string{"prog", `C:\dir" x`}},
+		{`prog C:\dir\ x`, []string{"prog", `C:\dir\`, "x"}},
+	}
+
+	for _, tst := range tests {
+		argv := shelltoken.SplitWindowsArgv(tst.in)
+		assert.Equalf(t, tst.res, argv, "SplitWindowsArgv: %s -> %#v", tst.in, argv)
+	}
+}
+
+func TestSplitWindowsArgvProgramName(t *testing.T) {
+	// the same backslash patterns are literal in the program name but escape quotes in later arguments
+	tests := []struct {
+		in   string
+		prog string
+		arg  string
+	}{
+		{`a\b`, `a\b`, `a\b`},
+		{`a\\"b c"`, `a\\b c`, `a\b c`},
+		{`a\"b\"`, `a\b\`, `a"b"`},
+		{`"a b\\"`, `a b\\`, `a b\`},
+		{`"a b\\\\"`, `a b\\\\`, `a b\\`},
+		{`"a""b"`, `ab`, `a"b`},
+	}
+
+	for _, tst := range tests {
+		argv := shelltoken.SplitWindowsArgv(tst.in + " " + tst.in)
+		if assert.Lenf(t, argv, 2, "SplitWindowsArgv: %s -> %#v", tst.in, argv) {
+			assert.Equalf(t, tst.prog, argv[0], "program name of: %s", tst.in)
+			assert.Equalf(t, tst.arg, argv[1], "argument of: %s", tst.in)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package shelltoken
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// zshOutsideQuoteShellCharacters are the shell characters for SplitZsh,
+// an unmatched closing parenthesis is not special in zsh.
+var zshOutsideQuoteShellCharacters = strings.ReplaceAll(OutsideQuoteShellCharacters, ")", "")
+
+// SplitZsh will tokenize a string the way zsh does it.
+// It works like SplitLinux and additionally
+// - keeps "=(cmd)" process substitutions at the start of a word verbatim.
+// - decodes ANSI-C quoted strings like $'a\tb'.
+// - does not treat an unmatched ")" as shell character.
+// Environment assignments are extracted like in SplitLinux.
+// returns error if shell characters were found.
+func SplitZsh(str string) (env, argv []string, err error) {
+	cfg := NewConfig(Whitespace, linuxOptions).WithShellCharacters(zshOutsideQuoteShellCharacters, "")
+	pst := newParseState(&cfg)
+	pst.zsh = true
+
+	argv = []string{}
+
+	err = pst.parse(strings.TrimSpace(str), func(token string, _, _ int) bool {
+		argv = append(argv, token)
+
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	env, argv = ExtractEnvFromArgv(argv)
+	if len(argv) == 0 {
+		// input consisting only of assignments has an empty command
+		argv = []string{""}
+	}
+
+	return env, argv, nil
+}
+
+// isZshProcessSubstitution returns true if a zsh "=(" process substitution starts a word at pos.
+func (p *parseState) isZshProcessSubstitution(char rune, pos int) bool {
+	if !p.zsh || char != '=' || p.hasToken || p.inSingleQuotes || p.inDoubleQuotes {
+		return false
+	}
+
+	return pos+1 < len(p.str) && p.str[pos+1] == '('
+}
+
+// isANSICQuote returns true if an ANSI-C quoted string "$'" starts at pos.
+func (p *parseState) isANSICQuote(char rune, pos int) bool {
+	if !p.zsh || char != '$' || p.inSingleQuotes || p.inDoubleQuotes {
+		return false
+	}
+
+	return pos+1 < len(p.str) && p.str[pos+1] == '\''
+}
+
+// addANSICQuote decodes the ANSI-C quoted string starting at pos and adds it to the current token.
+// Returns false if the closing quote is missing.
+func (p *parseState) addANSICQuote(pos int) bool {
+	buf := strings.Builder{}
+
+	for end := pos + 2; end < len(p.str); {
+		switch p.str[end] {
+		case '\'':
+			p.markToken(pos)
+			p.hasToken = true
+			p.token.WriteString(buf.String())
+			p.skip = end + 1
+
+			return true
+		case '\\':
+			end = decodeANSICEscape(&buf, p.str, end+1)
+		default:
+			char, size := utf8.DecodeRuneInString(p.str[end:])
+			buf.WriteRune(char)
+			end += size
+		}
+	}
+
+	return false
+}
+
+// decodeANSICEscape writes the escape sequence starting at pos (right after the backslash)
+// to buf and returns the position after it.
+func decodeANSICEscape(buf *strings.Builder, str string, pos int) int {
+	if pos >= len(str) {
+		buf.WriteByte('\\')
+
+		return pos
+	}
+
+	switch char := str[pos]; char {
+	case 'a':
+		buf.WriteByte('\a')
+	case 'b':
+		buf.WriteByte('\b')
+	case 'e', 'E':
+		buf.WriteByte(0x1b)
+	case 'f':
+		buf.WriteByte('\f')
+	case 'n':
+		buf.WriteByte('\n')
+	case 'r':
+		buf.WriteByte('\r')
+	case 't':
+		buf.WriteByte('\t')
+	case 'v':
+		buf.WriteByte('\v')
+	case '\\', '\'', '"', '?':
+		buf.WriteByte(char)
+	case 'c':
+		if pos+1 < len(str) {
+			buf.WriteByte(str[pos+1] & 0x1f)
+
+			return pos + 2
+		}
+
+		buf.WriteString(`\c`)
+	case 'x':
+		return decodeANSICNumber(buf, str, pos+1, 16, 2, `\x`)
+	case 'u':
+		return decodeANSICNumber(buf, str, pos+1, 16, 4, `\u`)
+	case 'U':
+		return decodeANSICNumber(buf, str, pos+1, 16, 8, `\U`)
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		return decodeANSICNumber(buf, str, pos, 8, 3, "")
+	default:
+		// unknown escape sequences are kept
+		buf.WriteByte('\\')
+
+		return pos
+	}
+
+	return pos + 1
+}
+
+// decodeANSICNumber decodes up to maxDigits digits in the given base starting at pos.
+// Values of \u and \U are written as utf-8, others as single byte. If there is no
+// digit, prefix is written literally instead.
+func decodeANSICNumber(buf *strings.Builder, str string, pos, base, maxDigits int, prefix string) int {
+	end := pos
+	for end < len(str) && end-pos < maxDigits && isDigitInBase(str[end], base) {
+		end++
+	}
+
+	if end == pos {
+		buf.WriteString(prefix)
+
+		return pos
+	}
+
+	num, _ := strconv.ParseUint(str[pos:end], base, 32)
+
+	switch {
+	case maxDigits > 2 && base == 16:
+		buf.WriteRune(rune(num))
+	default:
+		buf.WriteByte(byte(num))
+	}
+
+	return end
+}
+
+// isDigitInBase returns true if char is a valid digit in base 8 or 16.
+func isDigitInBase(char byte, base int) bool {
+	switch {
+	case char >= '0' && char <= '7':
+		return true
+	case base == 8:
+		return false
+	case char >= '8' && char <= '9', char >= 'a' && char <= 'f', char >= 'A' && char <= 'F':
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,53 @@
+package shelltoken_test
+
+import (
+	"testing"
+
+	"github.com/sni/shelltoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitZsh(t *testing.T) {
+	tests := []struct {
+		in   string
+		env  []string
+		argv []string
+	}{
+		{"", []string{}, []string{""}},
+		{"cat =(echo hi)", []string{}, []string{"cat", "=(echo hi)"}},
+		{"diff =(ls 'a)') =(ls \"(b\")", []string{}, []string{"diff", "=(ls 'a)')", "=(ls \"(b\")"}},
+		{"A=1 cat =(echo hi)", []string{"A=1"}, []string{"cat", "=(echo hi)"}},
+		{"echo a=b x)", []string{}, []string{"echo", "a=b", "x)"}},
+		{`echo $'a\tb' $'it\'s' x$'\n'y`, []string{}, []string{"echo", "a\tb", "it's", "x\ny"}},
+		{`echo $'\x41\101é\U0001F600\e\cA'`, []string{}, []string{"echo", "AAé😀\x1b\x01"}},
+		{`echo $'\q\x' '$\'`, []string{}, []string{"echo", `\q\x`, `$\`}},
+	}
+
+	for _, tst := range tests {
+		env, argv, err := shelltoken.SplitZsh(tst.in)
+		require.NoErrorf(t, err, "error while parsing: %s", tst.in)
+		assert.Equalf(t, tst.env, env, "SplitZsh env: %v -> %v", tst.in, env)
+		assert.Equalf(t, tst.argv, argv, "SplitZsh: %v -> %v", tst.in, argv)
+	}
+}
+
+func TestSplitZshErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		err error
+	}{
+		{"cat =(echo hi", shelltoken.ErrUnbalancedQuotes},
+		{"echo $'abc", shelltoken.ErrUnbalancedQuotes},
+		{"echo (a", shelltoken.ErrShellCharacters},
+		{"echo $HOME", shelltoken.ErrShellCharacters},
+		{"echo a=(b)", shelltoken.ErrShellCharacters},
+	}
+
+	for _, tst := range tests {
+		env, argv, err := shelltoken.SplitZsh(tst.in)
+		require.ErrorIsf(t, err, tst.err, "error for: %s", tst.in)
+		assert.Nil(t, env)
+		assert.Nil(t, argv)
+	}
+}